@@ -3,6 +3,11 @@ module gator
 go 1.25.1
 
 require (
-	github.com/google/uuid v1.6.0 // indirect
-	github.com/lib/pq v1.10.9 // indirect
+	github.com/google/uuid v1.6.0
+	github.com/lib/pq v1.10.9
+)
+
+require (
+	golang.org/x/net v0.46.0
+	golang.org/x/text v0.30.0
 )
@@ -0,0 +1,62 @@
+// Package canonical normalizes URLs so that variants of the same address
+// compare equal
+package canonical
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// urlPattern splits a URL into scheme, authority, path, query and fragment
+// the way the canonical_url SQL function of the 016 migration does, the two
+// have to agree for URLs stored before and after it.
+var urlPattern = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9+.-]*)://([^/?#]*)([^?#]*)(\?[^#]*)?(#.*)?$`)
+
+// URL returns the canonical form of an http or https URL, used as the key
+// feeds and posts are compared by:
+//   - the scheme is https and the host is lowercased, without www. and
+//     without the default port
+//   - trailing slashes of the path are dropped
+//   - utm_* tracking parameters, fbclid and gclid are dropped and the other
+//     query parameters sorted
+//
+// The fragment is kept since some feeds tell their items apart by it. Other
+// values, guids that aren't URLs for example, are only trimmed.
+func URL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	parts := urlPattern.FindStringSubmatch(raw)
+	if parts == nil {
+		return raw
+	}
+	scheme := strings.ToLower(parts[1])
+	if scheme != "http" && scheme != "https" {
+		return raw
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(parts[2]), "www.")
+	host = strings.TrimSuffix(strings.TrimSuffix(host, ":80"), ":443")
+	path := strings.TrimRight(parts[3], "/")
+
+	var params []string
+	for _, param := range strings.Split(strings.TrimPrefix(parts[4], "?"), "&") {
+		if param != "" && !isTracking(param) {
+			params = append(params, param)
+		}
+	}
+	sort.Strings(params)
+	query := ""
+	if len(params) > 0 {
+		query = "?" + strings.Join(params, "&")
+	}
+
+	return "https://" + host + path + query + parts[5]
+}
+
+// isTracking tells whether a query parameter only tracks where a visitor
+// came from.
+func isTracking(param string) bool {
+	key, _, _ := strings.Cut(param, "=")
+	key = strings.ToLower(key)
+	return strings.HasPrefix(key, "utm_") || key == "fbclid" || key == "gclid"
+}
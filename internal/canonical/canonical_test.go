@@ -0,0 +1,118 @@
+package canonical
+
+import (
+	"database/sql"
+	"os"
+	"strings"
+	"testing"
+
+	_ "github.com/lib/pq"
+)
+
+// tests are shared by the Go implementation and the canonical_url function
+// of the 016 migration, which have to agree.
+var tests = []struct {
+	raw  string
+	want string
+}{
+	// scheme
+	{"http://example.com/feed.xml", "https://example.com/feed.xml"},
+	{"https://example.com/feed.xml", "https://example.com/feed.xml"},
+	{"HTTP://example.com/feed.xml", "https://example.com/feed.xml"},
+	// host
+	{"https://www.example.com/feed.xml", "https://example.com/feed.xml"},
+	{"https://WWW.Example.COM/Feed.xml", "https://example.com/Feed.xml"},
+	{"https://blog.www.example.com/", "https://blog.www.example.com"},
+	// port
+	{"http://example.com:80/feed", "https://example.com/feed"},
+	{"https://example.com:443/feed", "https://example.com/feed"},
+	{"https://example.com:8080/feed", "https://example.com:8080/feed"},
+	// trailing slashes
+	{"https://example.com/", "https://example.com"},
+	{"https://example.com", "https://example.com"},
+	{"https://example.com/blog//", "https://example.com/blog"},
+	{"https://example.com/blog/?page=2", "https://example.com/blog?page=2"},
+	// tracking parameters
+	{"https://example.com/post?utm_source=rss&utm_medium=feed", "https://example.com/post"},
+	{"https://example.com/post?id=7&UTM_Campaign=spring", "https://example.com/post?id=7"},
+	{"https://example.com/post?fbclid=abc&id=7&gclid=def", "https://example.com/post?id=7"},
+	{"https://example.com/post?utm=1", "https://example.com/post?utm=1"},
+	// query ordering
+	{"https://example.com/search?q=go&a=1", "https://example.com/search?a=1&q=go"},
+	{"https://example.com/search?b=2&B=1&a", "https://example.com/search?B=1&a&b=2"},
+	{"https://example.com/search?&q=go&&", "https://example.com/search?q=go"},
+	{"https://example.com/search?", "https://example.com/search"},
+	// fragments
+	{"https://example.com/post#comments", "https://example.com/post#comments"},
+	{"https://example.com/post/?utm_source=x#part-2", "https://example.com/post#part-2"},
+	{"https://example.com/#", "https://example.com#"},
+	// values that aren't http URLs
+	{"  https://example.com/feed/ \n", "https://example.com/feed"},
+	{"tag:example.com,2024:post-1", "tag:example.com,2024:post-1"},
+	{"urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6a", "urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6a"},
+	{"42", "42"},
+	{" post 42 ", "post 42"},
+	{"ftp://www.example.com/file/", "ftp://www.example.com/file/"},
+	{"example.com/feed", "example.com/feed"},
+	{"", ""},
+}
+
+func TestURL(t *testing.T) {
+	for _, test := range tests {
+		if got := URL(test.raw); got != test.want {
+			t.Errorf("URL(%q) = %q, want %q", test.raw, got, test.want)
+		}
+	}
+}
+
+func TestURLIdempotent(t *testing.T) {
+	for _, test := range tests {
+		if got := URL(test.want); got != test.want {
+			t.Errorf("URL(%q) = %q, the canonical form should stay as it is", test.want, got)
+		}
+	}
+}
+
+// TestMigrationSQL runs the cases against the canonical_url function of the
+// 016 migration, in a database given by GATOR_TEST_DB_URL.
+func TestMigrationSQL(t *testing.T) {
+	dbURL := os.Getenv("GATOR_TEST_DB_URL")
+	if dbURL == "" {
+		t.Skip("GATOR_TEST_DB_URL isn't set")
+	}
+
+	migration, err := os.ReadFile("../../sql/schema/016_canonical_urls.sql")
+	if err != nil {
+		t.Fatalf("failed reading the migration: %s", err)
+	}
+	_, function, _ := strings.Cut(string(migration), "-- +goose StatementBegin\n")
+	function, _, found := strings.Cut(function, "-- +goose StatementEnd")
+	if !found {
+		t.Fatal("the migration has no canonical_url function")
+	}
+	// a temporary function is dropped with the session and never clashes
+	// with the database
+	function = strings.Replace(function, "CREATE FUNCTION canonical_url", "CREATE FUNCTION pg_temp.canonical_url", 1)
+
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatalf("failed opening the database: %s", err)
+	}
+	defer db.Close()
+	// pg_temp belongs to a single connection
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(function); err != nil {
+		t.Fatalf("failed creating canonical_url: %s", err)
+	}
+
+	for _, test := range tests {
+		var got string
+		if err := db.QueryRow("SELECT pg_temp.canonical_url($1)", test.raw).Scan(&got); err != nil {
+			t.Errorf("canonical_url(%q) failed: %s", test.raw, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("canonical_url(%q) = %q, want %q", test.raw, got, test.want)
+		}
+	}
+}
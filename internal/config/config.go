@@ -5,13 +5,174 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
 const configFileName = ".gatorconfig.json"
 
 type Config struct {
-	DbUrl           string `json:"db_url"`
-	CurrentUserName string `json:"current_user_name"`
+	DbUrl           string         `json:"db_url"`
+	CurrentUserName string         `json:"current_user_name"`
+	Agg             AggConfig      `json:"agg"`
+	HTTP            HTTPConfig     `json:"http"`
+	Download        DownloadConfig `json:"download"`
+}
+
+// DownloadConfig configures the download command, missing values are set to
+// the defaults when the config is read.
+type DownloadConfig struct {
+	// Dir is where enclosures are downloaded to, in a directory per feed, a
+	// leading ~/ stands for the home directory
+	Dir string `json:"dir,omitempty"`
+	// Keep is the number of the newest posts with enclosures kept per feed,
+	// the files of older ones are removed
+	Keep int `json:"keep,omitempty"`
+	// KeepPerFeed overrides Keep for single feeds, keyed by the feed URL
+	KeepPerFeed map[string]int `json:"keep_per_feed,omitempty"`
+}
+
+// HTTPConfig configures how feeds are fetched, missing values are set to the
+// defaults when the config is read.
+type HTTPConfig struct {
+	// UserAgent is sent with every request, gator identifies itself by default
+	UserAgent string `json:"user_agent,omitempty"`
+	// ConnectTimeout bounds dialing and the TLS handshake
+	ConnectTimeout Duration `json:"connect_timeout,omitempty"`
+	// ReadTimeout bounds waiting for the response and every read of its body
+	ReadTimeout Duration `json:"read_timeout,omitempty"`
+	// MaxBodySize is the largest feed accepted, in bytes
+	MaxBodySize int64 `json:"max_body_size,omitempty"`
+	// MaxItems is the number of items read per fetch, the first ones of the
+	// feed which usually are the newest, 0 reads all of them. It's a pointer
+	// to tell an explicit 0 from a missing value.
+	MaxItems *int `json:"max_items,omitempty"`
+	// Proxy is used instead of the HTTP_PROXY/HTTPS_PROXY environment
+	Proxy string `json:"proxy,omitempty"`
+	// CABundle is a PEM file with extra certificates to trust
+	CABundle string `json:"ca_bundle,omitempty"`
+	// HostConcurrency and HostDelay limit the requests to a single host,
+	// a feed waiting longer than HostMaxWait for its turn is deferred
+	HostConcurrency int      `json:"host_concurrency,omitempty"`
+	HostDelay       Duration `json:"host_delay,omitempty"`
+	HostMaxWait     Duration `json:"host_max_wait,omitempty"`
+	// Robots makes gator obey robots.txt
+	Robots bool `json:"robots,omitempty"`
+}
+
+// AggConfig configures the agg command, missing values are set to the
+// defaults when the config is read.
+type AggConfig struct {
+	// BatchSize is the number of feeds fetched per cycle
+	BatchSize int `json:"batch_size,omitempty"`
+	// Workers is the number of feeds fetched concurrently
+	Workers int `json:"workers,omitempty"`
+	// FetchTimeout bounds fetching and storing a single feed
+	FetchTimeout Duration `json:"fetch_timeout,omitempty"`
+	// LeaseDuration is how long a feed is reserved for this process, other
+	// agg processes reclaim it afterwards, so it should exceed FetchTimeout
+	LeaseDuration Duration `json:"lease_duration,omitempty"`
+	// BackoffBase is how long a feed waits after its first failed fetch, the
+	// wait doubles with every further failure up to BackoffMax
+	BackoffBase Duration `json:"backoff_base,omitempty"`
+	BackoffMax  Duration `json:"backoff_max,omitempty"`
+	// MaxFailures is the number of failed fetches in a row after which a
+	// feed is disabled
+	MaxFailures int `json:"max_failures,omitempty"`
+	// MinInterval and MaxInterval bound the time between two fetches of a
+	// feed, which is otherwise adapted to how often the feed publishes
+	MinInterval Duration `json:"min_interval,omitempty"`
+	MaxInterval Duration `json:"max_interval,omitempty"`
+	// ShutdownGrace is how long in-flight fetches may still run after agg
+	// is asked to stop
+	ShutdownGrace Duration `json:"shutdown_grace,omitempty"`
+}
+
+// Duration is a time.Duration stored in the config file as a string like "30s".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("duration must be a string like \"30s\": %s", err)
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (c *Config) setDefaults() {
+	if c.Agg.BatchSize <= 0 {
+		c.Agg.BatchSize = 10
+	}
+	if c.Agg.Workers <= 0 {
+		c.Agg.Workers = 4
+	}
+	if c.Agg.FetchTimeout <= 0 {
+		c.Agg.FetchTimeout = Duration(30 * time.Second)
+	}
+	if c.Agg.LeaseDuration <= 0 {
+		c.Agg.LeaseDuration = Duration(5 * time.Minute)
+	}
+	if c.Agg.BackoffBase <= 0 {
+		c.Agg.BackoffBase = Duration(5 * time.Minute)
+	}
+	if c.Agg.BackoffMax <= 0 {
+		c.Agg.BackoffMax = Duration(24 * time.Hour)
+	}
+	if c.Agg.MaxFailures <= 0 {
+		c.Agg.MaxFailures = 10
+	}
+	if c.Agg.MinInterval <= 0 {
+		c.Agg.MinInterval = Duration(15 * time.Minute)
+	}
+	if c.Agg.MaxInterval <= 0 {
+		c.Agg.MaxInterval = Duration(24 * time.Hour)
+	}
+	if c.Agg.ShutdownGrace <= 0 {
+		c.Agg.ShutdownGrace = Duration(10 * time.Second)
+	}
+	if c.Download.Dir == "" {
+		c.Download.Dir = "~/gator/downloads"
+	}
+	if rest, ok := strings.CutPrefix(c.Download.Dir, "~/"); ok {
+		if userHome, err := os.UserHomeDir(); err == nil {
+			c.Download.Dir = filepath.Join(userHome, rest)
+		}
+	}
+	if c.Download.Keep <= 0 {
+		c.Download.Keep = 3
+	}
+	if c.HTTP.ConnectTimeout <= 0 {
+		c.HTTP.ConnectTimeout = Duration(10 * time.Second)
+	}
+	if c.HTTP.ReadTimeout <= 0 {
+		c.HTTP.ReadTimeout = Duration(30 * time.Second)
+	}
+	if c.HTTP.MaxBodySize <= 0 {
+		c.HTTP.MaxBodySize = 10 << 20
+	}
+	if c.HTTP.MaxItems == nil || *c.HTTP.MaxItems < 0 {
+		maxItems := 500
+		c.HTTP.MaxItems = &maxItems
+	}
+	if c.HTTP.HostConcurrency <= 0 {
+		c.HTTP.HostConcurrency = 2
+	}
+	if c.HTTP.HostDelay <= 0 {
+		c.HTTP.HostDelay = Duration(time.Second)
+	}
+	if c.HTTP.HostMaxWait <= 0 {
+		c.HTTP.HostMaxWait = Duration(10 * time.Second)
+	}
 }
 
 func getConfigFilePath() (string, error) {
@@ -22,12 +183,33 @@ func getConfigFilePath() (string, error) {
 	return fmt.Sprintf("%s/%s", userHome, configFileName), nil
 }
 
-func write(cfg Config) error {
+// write stores the given fields in the config file and keeps all others as
+// they are, so the defaults filled in by Read never end up in the file and
+// later changes to them still apply.
+func write(fields map[string]any) error {
 	configFile, err := getConfigFilePath()
 	if err != nil {
 		return fmt.Errorf("failed writing to config file: %s", err)
 	}
-	confBytes, err := json.Marshal(cfg)
+	confBytes, err := os.ReadFile(configFile)
+	if err != nil {
+		return fmt.Errorf("failed reading gatorconfig: %s", err)
+	}
+	var stored map[string]json.RawMessage
+	if err := json.Unmarshal(confBytes, &stored); err != nil {
+		return fmt.Errorf("failed unmarshaling config file: %s", err)
+	}
+	if stored == nil {
+		stored = make(map[string]json.RawMessage)
+	}
+	for name, value := range fields {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("failed marshaling config field %s: %s", name, err)
+		}
+		stored[name] = encoded
+	}
+	confBytes, err = json.Marshal(stored)
 	if err != nil {
 		return fmt.Errorf("failed marshaling config file: %s", err)
 	}
@@ -39,7 +221,7 @@ func Read() (*Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed reading config dir: %s", err)
 	}
-	confBytes, err := os.ReadFile(fmt.Sprintf(configPath))
+	confBytes, err := os.ReadFile(configPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed reading gatorconfig: %s", err)
 	}
@@ -47,12 +229,13 @@ func Read() (*Config, error) {
 	if err := json.Unmarshal(confBytes, &conf); err != nil {
 		return nil, fmt.Errorf("failed unmarshaling config file: %s", err)
 	}
+	conf.setDefaults()
 	return conf, nil
 }
 
 func (c *Config) SetUser(userName string) error {
 	c.CurrentUserName = userName
-	if err := write(*c); err != nil {
+	if err := write(map[string]any{"current_user_name": userName}); err != nil {
 		return err
 	}
 	return nil
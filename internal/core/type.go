@@ -1,13 +1,22 @@
 package core
 
 import (
+	"context"
+	"database/sql"
 	cfg "gator/internal/config"
 	"gator/internal/database"
+	"gator/internal/rss"
 )
 
 type State struct {
-	Db     *database.Queries
-	Config *cfg.Config
+	// Ctx is the root context of the command, it's canceled on SIGINT/SIGTERM
+	Ctx context.Context
+	Db  *database.Queries
+	// DbConn is the connection pool behind Db, for statements that have to
+	// run in a transaction
+	DbConn  *sql.DB
+	Config  *cfg.Config
+	Fetcher *rss.Fetcher
 }
 
 type Command struct {
@@ -14,23 +14,25 @@ import (
 )
 
 const createFeed = `-- name: CreateFeed :one
-INSERT INTO feeds (id, name, url, user_id, created_at, updated_at)
+INSERT INTO feeds (id, name, url, original_url, user_id, created_at, updated_at)
 VALUES ($1,
         $2,
         $3,
         $4,
         $5,
-        $6)
-RETURNING id, name, url, user_id, created_at, updated_at, last_fetched_at
+        $6,
+        $7)
+RETURNING id, name, url, user_id, created_at, updated_at, last_fetched_at, etag, last_modified, title, description, site_url, language, image_url, generator, lease_expires_at, consecutive_failures, last_error, last_status, last_success_at, next_fetch_at, disabled_at, original_url
 `
 
 type CreateFeedParams struct {
-	ID        uuid.UUID
-	Name      string
-	Url       string
-	UserID    uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID          uuid.UUID
+	Name        string
+	Url         string
+	OriginalUrl string
+	UserID      uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
 }
 
 func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error) {
@@ -38,6 +40,7 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		arg.ID,
 		arg.Name,
 		arg.Url,
+		arg.OriginalUrl,
 		arg.UserID,
 		arg.CreatedAt,
 		arg.UpdatedAt,
@@ -51,6 +54,22 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.Title,
+		&i.Description,
+		&i.SiteUrl,
+		&i.Language,
+		&i.ImageUrl,
+		&i.Generator,
+		&i.LeaseExpiresAt,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastStatus,
+		&i.LastSuccessAt,
+		&i.NextFetchAt,
+		&i.DisabledAt,
+		&i.OriginalUrl,
 	)
 	return i, err
 }
@@ -111,8 +130,80 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 	return i, err
 }
 
+const enableFeed = `-- name: EnableFeed :execrows
+UPDATE feeds
+SET disabled_at          = NULL,
+    consecutive_failures = 0,
+    next_fetch_at        = NULL,
+    updated_at           = NOW()
+WHERE url = $1
+`
+
+func (q *Queries) EnableFeed(ctx context.Context, url string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, enableFeed, url)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getBrokenFeeds = `-- name: GetBrokenFeeds :many
+SELECT id, name, url, user_id, created_at, updated_at, last_fetched_at, etag, last_modified, title, description, site_url, language, image_url, generator, lease_expires_at, consecutive_failures, last_error, last_status, last_success_at, next_fetch_at, disabled_at, original_url
+FROM feeds
+WHERE consecutive_failures > 0
+   OR disabled_at IS NOT NULL
+ORDER BY disabled_at NULLS LAST, consecutive_failures DESC
+`
+
+func (q *Queries) GetBrokenFeeds(ctx context.Context) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getBrokenFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.Title,
+			&i.Description,
+			&i.SiteUrl,
+			&i.Language,
+			&i.ImageUrl,
+			&i.Generator,
+			&i.LeaseExpiresAt,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.LastStatus,
+			&i.LastSuccessAt,
+			&i.NextFetchAt,
+			&i.DisabledAt,
+			&i.OriginalUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getFeedByUrl = `-- name: GetFeedByUrl :one
-SELECT id, name, url, user_id, created_at, updated_at, last_fetched_at
+SELECT id, name, url, user_id, created_at, updated_at, last_fetched_at, etag, last_modified, title, description, site_url, language, image_url, generator, lease_expires_at, consecutive_failures, last_error, last_status, last_success_at, next_fetch_at, disabled_at, original_url
 FROM feeds
 WHERE url = $1
 `
@@ -128,12 +219,28 @@ func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (Feed, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.Title,
+		&i.Description,
+		&i.SiteUrl,
+		&i.Language,
+		&i.ImageUrl,
+		&i.Generator,
+		&i.LeaseExpiresAt,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastStatus,
+		&i.LastSuccessAt,
+		&i.NextFetchAt,
+		&i.DisabledAt,
+		&i.OriginalUrl,
 	)
 	return i, err
 }
 
 const getFeedsForUser = `-- name: GetFeedsForUser :many
-SELECT f.id, f.name, f.url, f.user_id, f.created_at, f.updated_at, f.last_fetched_at, u.name AS user_name
+SELECT f.id, f.name, f.url, f.user_id, f.created_at, f.updated_at, f.last_fetched_at, f.etag, f.last_modified, f.title, f.description, f.site_url, f.language, f.image_url, f.generator, f.lease_expires_at, f.consecutive_failures, f.last_error, f.last_status, f.last_success_at, f.next_fetch_at, f.disabled_at, f.original_url, u.name AS user_name
 FROM feeds f
          JOIN feed_follows ff ON ff.feed_id = f.id
          JOIN users u ON u.id = ff.user_id
@@ -141,14 +248,30 @@ WHERE u.name = $1
 `
 
 type GetFeedsForUserRow struct {
-	ID            uuid.UUID
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	LastFetchedAt sql.NullTime
-	UserName      string
+	ID                  uuid.UUID
+	Name                string
+	Url                 string
+	UserID              uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	LastFetchedAt       sql.NullTime
+	Etag                sql.NullString
+	LastModified        sql.NullString
+	Title               sql.NullString
+	Description         sql.NullString
+	SiteUrl             sql.NullString
+	Language            sql.NullString
+	ImageUrl            sql.NullString
+	Generator           sql.NullString
+	LeaseExpiresAt      sql.NullTime
+	ConsecutiveFailures int32
+	LastError           sql.NullString
+	LastStatus          sql.NullInt32
+	LastSuccessAt       sql.NullTime
+	NextFetchAt         sql.NullTime
+	DisabledAt          sql.NullTime
+	OriginalUrl         string
+	UserName            string
 }
 
 func (q *Queries) GetFeedsForUser(ctx context.Context, name string) ([]GetFeedsForUserRow, error) {
@@ -168,6 +291,22 @@ func (q *Queries) GetFeedsForUser(ctx context.Context, name string) ([]GetFeedsF
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.Title,
+			&i.Description,
+			&i.SiteUrl,
+			&i.Language,
+			&i.ImageUrl,
+			&i.Generator,
+			&i.LeaseExpiresAt,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.LastStatus,
+			&i.LastSuccessAt,
+			&i.NextFetchAt,
+			&i.DisabledAt,
+			&i.OriginalUrl,
 			&i.UserName,
 		); err != nil {
 			return nil, err
@@ -184,20 +323,36 @@ func (q *Queries) GetFeedsForUser(ctx context.Context, name string) ([]GetFeedsF
 }
 
 const getFeedsWithUserName = `-- name: GetFeedsWithUserName :many
-SELECT f.id, f.name, f.url, f.user_id, f.created_at, f.updated_at, f.last_fetched_at, u.name AS user_name
+SELECT f.id, f.name, f.url, f.user_id, f.created_at, f.updated_at, f.last_fetched_at, f.etag, f.last_modified, f.title, f.description, f.site_url, f.language, f.image_url, f.generator, f.lease_expires_at, f.consecutive_failures, f.last_error, f.last_status, f.last_success_at, f.next_fetch_at, f.disabled_at, f.original_url, u.name AS user_name
 FROM feeds f
          JOIN users u ON u.id = f.user_id
 `
 
 type GetFeedsWithUserNameRow struct {
-	ID            uuid.UUID
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	LastFetchedAt sql.NullTime
-	UserName      string
+	ID                  uuid.UUID
+	Name                string
+	Url                 string
+	UserID              uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	LastFetchedAt       sql.NullTime
+	Etag                sql.NullString
+	LastModified        sql.NullString
+	Title               sql.NullString
+	Description         sql.NullString
+	SiteUrl             sql.NullString
+	Language            sql.NullString
+	ImageUrl            sql.NullString
+	Generator           sql.NullString
+	LeaseExpiresAt      sql.NullTime
+	ConsecutiveFailures int32
+	LastError           sql.NullString
+	LastStatus          sql.NullInt32
+	LastSuccessAt       sql.NullTime
+	NextFetchAt         sql.NullTime
+	DisabledAt          sql.NullTime
+	OriginalUrl         string
+	UserName            string
 }
 
 func (q *Queries) GetFeedsWithUserName(ctx context.Context) ([]GetFeedsWithUserNameRow, error) {
@@ -217,6 +372,22 @@ func (q *Queries) GetFeedsWithUserName(ctx context.Context) ([]GetFeedsWithUserN
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.Title,
+			&i.Description,
+			&i.SiteUrl,
+			&i.Language,
+			&i.ImageUrl,
+			&i.Generator,
+			&i.LeaseExpiresAt,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.LastStatus,
+			&i.LastSuccessAt,
+			&i.NextFetchAt,
+			&i.DisabledAt,
+			&i.OriginalUrl,
 			&i.UserName,
 		); err != nil {
 			return nil, err
@@ -232,35 +403,82 @@ func (q *Queries) GetFeedsWithUserName(ctx context.Context) ([]GetFeedsWithUserN
 	return items, nil
 }
 
-const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, name, url, user_id, created_at, updated_at, last_fetched_at
-FROM feeds
-ORDER BY last_fetched_at NULLS FIRST
-LIMIT 1
+const leaseFeedsToFetch = `-- name: LeaseFeedsToFetch :many
+UPDATE feeds
+SET lease_expires_at = NOW() + $1::INTEGER * INTERVAL '1 second'
+WHERE id IN (SELECT id
+             FROM feeds
+             WHERE (lease_expires_at IS NULL OR lease_expires_at < NOW())
+               AND (next_fetch_at IS NULL OR next_fetch_at <= NOW())
+               AND disabled_at IS NULL
+             ORDER BY next_fetch_at NULLS FIRST, last_fetched_at NULLS FIRST
+             LIMIT $2 FOR UPDATE SKIP LOCKED)
+RETURNING id, name, url, user_id, created_at, updated_at, last_fetched_at, etag, last_modified, title, description, site_url, language, image_url, generator, lease_expires_at, consecutive_failures, last_error, last_status, last_success_at, next_fetch_at, disabled_at, original_url
 `
 
-func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
-	var i Feed
-	err := row.Scan(
-		&i.ID,
-		&i.Name,
-		&i.Url,
-		&i.UserID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.LastFetchedAt,
-	)
-	return i, err
+type LeaseFeedsToFetchParams struct {
+	LeaseSeconds int32
+	BatchSize    int32
+}
+
+// Claims the next feeds to fetch for lease_seconds. Feeds locked or leased by
+// another agg process are skipped, expired leases of crashed ones are reclaimed.
+func (q *Queries) LeaseFeedsToFetch(ctx context.Context, arg LeaseFeedsToFetchParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, leaseFeedsToFetch, arg.LeaseSeconds, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.Title,
+			&i.Description,
+			&i.SiteUrl,
+			&i.Language,
+			&i.ImageUrl,
+			&i.Generator,
+			&i.LeaseExpiresAt,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.LastStatus,
+			&i.LastSuccessAt,
+			&i.NextFetchAt,
+			&i.DisabledAt,
+			&i.OriginalUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const markFeedFetched = `-- name: MarkFeedFetched :execrows
 UPDATE feeds
-SET last_fetched_at = NOW(),
-    updated_at      = NOW()
+SET last_fetched_at  = NOW(),
+    lease_expires_at = NULL,
+    updated_at       = NOW()
 WHERE id = $1
 `
 
+// Marks the feed fetched and releases its lease.
 func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) (int64, error) {
 	result, err := q.db.ExecContext(ctx, markFeedFetched, id)
 	if err != nil {
@@ -269,6 +487,212 @@ func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) (int64, err
 	return result.RowsAffected()
 }
 
+const mergeFeed = `-- name: MergeFeed :exec
+WITH moved_follows AS (
+    INSERT INTO feed_follows (id, user_id, feed_id, created_at, updated_at)
+        SELECT gen_random_uuid(), user_id, $1::UUID, NOW(), NOW()
+        FROM feed_follows
+        WHERE feed_id = $2::UUID
+        ON CONFLICT (user_id, feed_id) DO NOTHING),
+     dropped_follows AS (
+         DELETE FROM feed_follows
+             WHERE feed_id = $2::UUID),
+     moved_posts AS (
+         UPDATE posts
+             SET feed_id = $1::UUID,
+                 updated_at = NOW()
+             WHERE feed_id = $2::UUID
+                 AND guid NOT IN (SELECT guid FROM posts WHERE feed_id = $1::UUID))
+UPDATE feeds
+SET disabled_at   = NOW(),
+    last_error    = $3,
+    next_fetch_at = NULL,
+    updated_at    = NOW()
+WHERE id = $2::UUID
+`
+
+type MergeFeedParams struct {
+	IntoID    uuid.UUID
+	FromID    uuid.UUID
+	LastError sql.NullString
+}
+
+// Merges a feed that moved to the URL of another feed into that one: its
+// followers follow the other feed, its posts missing there move over and the
+// feed itself is disabled.
+func (q *Queries) MergeFeed(ctx context.Context, arg MergeFeedParams) error {
+	_, err := q.db.ExecContext(ctx, mergeFeed, arg.IntoID, arg.FromID, arg.LastError)
+	return err
+}
+
+const postponeFeed = `-- name: PostponeFeed :exec
+UPDATE feeds
+SET last_error    = $1,
+    last_status   = $2,
+    next_fetch_at = NOW() + $3::INTEGER * INTERVAL '1 second'
+WHERE id = $4
+`
+
+type PostponeFeedParams struct {
+	LastError    sql.NullString
+	LastStatus   sql.NullInt32
+	RetrySeconds int32
+	ID           uuid.UUID
+}
+
+// Postpones the next fetch of a feed the server asked to retry later, that
+// doesn't count as a failure.
+func (q *Queries) PostponeFeed(ctx context.Context, arg PostponeFeedParams) error {
+	_, err := q.db.ExecContext(ctx, postponeFeed,
+		arg.LastError,
+		arg.LastStatus,
+		arg.RetrySeconds,
+		arg.ID,
+	)
+	return err
+}
+
+const recordFeedFailure = `-- name: RecordFeedFailure :one
+UPDATE feeds
+SET consecutive_failures = consecutive_failures + 1,
+    last_error           = $1,
+    last_status          = $2,
+    next_fetch_at        = NOW() + $3::INTEGER * INTERVAL '1 second',
+    disabled_at          = CASE
+                               WHEN consecutive_failures + 1 >= $4::INTEGER THEN NOW()
+        END
+WHERE id = $5
+RETURNING consecutive_failures, disabled_at
+`
+
+type RecordFeedFailureParams struct {
+	LastError      sql.NullString
+	LastStatus     sql.NullInt32
+	BackoffSeconds int32
+	MaxFailures    int32
+	ID             uuid.UUID
+}
+
+type RecordFeedFailureRow struct {
+	ConsecutiveFailures int32
+	DisabledAt          sql.NullTime
+}
+
+// Counts a failed fetch and postpones the feed by backoff_seconds, the feed is
+// disabled once it has failed max_failures times in a row.
+func (q *Queries) RecordFeedFailure(ctx context.Context, arg RecordFeedFailureParams) (RecordFeedFailureRow, error) {
+	row := q.db.QueryRowContext(ctx, recordFeedFailure,
+		arg.LastError,
+		arg.LastStatus,
+		arg.BackoffSeconds,
+		arg.MaxFailures,
+		arg.ID,
+	)
+	var i RecordFeedFailureRow
+	err := row.Scan(
+		&i.ConsecutiveFailures,
+		&i.DisabledAt,
+	)
+	return i, err
+}
+
+const recordFeedRedirect = `-- name: RecordFeedRedirect :exec
+INSERT INTO feed_redirects (feed_id, from_url, to_url)
+VALUES ($1, $2, $3)
+`
+
+type RecordFeedRedirectParams struct {
+	FeedID  uuid.UUID
+	FromUrl string
+	ToUrl   string
+}
+
+func (q *Queries) RecordFeedRedirect(ctx context.Context, arg RecordFeedRedirectParams) error {
+	_, err := q.db.ExecContext(ctx, recordFeedRedirect, arg.FeedID, arg.FromUrl, arg.ToUrl)
+	return err
+}
+
+const recordFeedSuccess = `-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET consecutive_failures = 0,
+    last_error           = NULL,
+    last_status          = $1,
+    last_success_at      = NOW(),
+    next_fetch_at        = NOW() + $2::INTEGER * INTERVAL '1 second'
+WHERE id = $3
+`
+
+type RecordFeedSuccessParams struct {
+	LastStatus       sql.NullInt32
+	NextFetchSeconds int32
+	ID               uuid.UUID
+}
+
+// Resets the failures of the feed and schedules its next fetch in
+// next_fetch_seconds.
+func (q *Queries) RecordFeedSuccess(ctx context.Context, arg RecordFeedSuccessParams) error {
+	_, err := q.db.ExecContext(ctx, recordFeedSuccess, arg.LastStatus, arg.NextFetchSeconds, arg.ID)
+	return err
+}
+
+const releaseFeedLease = `-- name: ReleaseFeedLease :exec
+UPDATE feeds
+SET lease_expires_at = NULL
+WHERE id = $1
+`
+
+// Releases the lease of a feed that wasn't fetched, so it's due again right away.
+func (q *Queries) ReleaseFeedLease(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, releaseFeedLease, id)
+	return err
+}
+
+const renewFeedLease = `-- name: RenewFeedLease :execrows
+UPDATE feeds
+SET lease_expires_at = NOW() + $1::INTEGER * INTERVAL '1 second'
+WHERE id = $2
+  AND lease_expires_at = $3
+`
+
+type RenewFeedLeaseParams struct {
+	LeaseSeconds int32
+	ID           uuid.UUID
+	LeasedUntil  sql.NullTime
+}
+
+// Extends the lease of a feed by lease_seconds when its fetch starts, as long
+// as it's still the lease taken until leased_until. Updates nothing when the
+// lease ran out and another agg process has leased the feed since.
+func (q *Queries) RenewFeedLease(ctx context.Context, arg RenewFeedLeaseParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, renewFeedLease, arg.LeaseSeconds, arg.ID, arg.LeasedUntil)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const retireFeed = `-- name: RetireFeed :exec
+UPDATE feeds
+SET disabled_at   = NOW(),
+    last_error    = $1,
+    last_status   = $2,
+    next_fetch_at = NULL,
+    updated_at    = NOW()
+WHERE id = $3
+`
+
+type RetireFeedParams struct {
+	LastError  sql.NullString
+	LastStatus sql.NullInt32
+	ID         uuid.UUID
+}
+
+// Disables a feed the server reported as gone for good.
+func (q *Queries) RetireFeed(ctx context.Context, arg RetireFeedParams) error {
+	_, err := q.db.ExecContext(ctx, retireFeed, arg.LastError, arg.LastStatus, arg.ID)
+	return err
+}
+
 const unfollowFeed = `-- name: UnfollowFeed :execrows
 DELETE
 FROM feed_follows
@@ -288,3 +712,76 @@ func (q *Queries) UnfollowFeed(ctx context.Context, arg UnfollowFeedParams) (int
 	}
 	return result.RowsAffected()
 }
+
+const updateFeedCacheValidators = `-- name: UpdateFeedCacheValidators :exec
+UPDATE feeds
+SET etag          = $2,
+    last_modified = $3,
+    updated_at    = NOW()
+WHERE id = $1
+`
+
+type UpdateFeedCacheValidatorsParams struct {
+	ID           uuid.UUID
+	Etag         sql.NullString
+	LastModified sql.NullString
+}
+
+func (q *Queries) UpdateFeedCacheValidators(ctx context.Context, arg UpdateFeedCacheValidatorsParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedCacheValidators, arg.ID, arg.Etag, arg.LastModified)
+	return err
+}
+
+const updateFeedMetadata = `-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET title       = $2,
+    description = $3,
+    site_url    = $4,
+    language    = $5,
+    image_url   = $6,
+    generator   = $7,
+    updated_at  = NOW()
+WHERE id = $1
+`
+
+type UpdateFeedMetadataParams struct {
+	ID          uuid.UUID
+	Title       sql.NullString
+	Description sql.NullString
+	SiteUrl     sql.NullString
+	Language    sql.NullString
+	ImageUrl    sql.NullString
+	Generator   sql.NullString
+}
+
+func (q *Queries) UpdateFeedMetadata(ctx context.Context, arg UpdateFeedMetadataParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedMetadata,
+		arg.ID,
+		arg.Title,
+		arg.Description,
+		arg.SiteUrl,
+		arg.Language,
+		arg.ImageUrl,
+		arg.Generator,
+	)
+	return err
+}
+
+const updateFeedUrl = `-- name: UpdateFeedUrl :exec
+UPDATE feeds
+SET url          = $2,
+    original_url = $3,
+    updated_at   = NOW()
+WHERE id = $1
+`
+
+type UpdateFeedUrlParams struct {
+	ID          uuid.UUID
+	Url         string
+	OriginalUrl string
+}
+
+func (q *Queries) UpdateFeedUrl(ctx context.Context, arg UpdateFeedUrlParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedUrl, arg.ID, arg.Url, arg.OriginalUrl)
+	return err
+}
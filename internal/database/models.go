@@ -11,14 +11,44 @@ import (
 	"github.com/google/uuid"
 )
 
+type Download struct {
+	ID          uuid.UUID
+	PostID      uuid.UUID
+	Url         string
+	Path        string
+	State       string
+	Bytes       int64
+	Sha256      sql.NullString
+	LastError   sql.NullString
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	CompletedAt sql.NullTime
+}
+
 type Feed struct {
-	ID            uuid.UUID
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	LastFetchedAt sql.NullTime
+	ID                  uuid.UUID
+	Name                string
+	Url                 string
+	UserID              uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	LastFetchedAt       sql.NullTime
+	Etag                sql.NullString
+	LastModified        sql.NullString
+	Title               sql.NullString
+	Description         sql.NullString
+	SiteUrl             sql.NullString
+	Language            sql.NullString
+	ImageUrl            sql.NullString
+	Generator           sql.NullString
+	LeaseExpiresAt      sql.NullTime
+	ConsecutiveFailures int32
+	LastError           sql.NullString
+	LastStatus          sql.NullInt32
+	LastSuccessAt       sql.NullTime
+	NextFetchAt         sql.NullTime
+	DisabledAt          sql.NullTime
+	OriginalUrl         string
 }
 
 type FeedFollow struct {
@@ -29,6 +59,14 @@ type FeedFollow struct {
 	UpdatedAt time.Time
 }
 
+type FeedRedirect struct {
+	ID        uuid.UUID
+	FeedID    uuid.UUID
+	FromUrl   string
+	ToUrl     string
+	CreatedAt time.Time
+}
+
 type Post struct {
 	ID          uuid.UUID
 	Title       string
@@ -38,6 +76,35 @@ type Post struct {
 	FeedID      uuid.UUID
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
+	Guid        string
+	Content     sql.NullString
+	Author      sql.NullString
+	ImageUrl    sql.NullString
+	Episode     sql.NullInt32
+	OriginalUrl sql.NullString
+}
+
+type PostCategory struct {
+	PostID uuid.UUID
+	Name   string
+}
+
+type PostEnclosure struct {
+	PostID          uuid.UUID
+	Url             string
+	MimeType        sql.NullString
+	Length          sql.NullInt64
+	DurationSeconds sql.NullInt32
+}
+
+type PostRevision struct {
+	ID          uuid.UUID
+	PostID      uuid.UUID
+	Title       string
+	Url         sql.NullString
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	CreatedAt   time.Time
 }
 
 type User struct {
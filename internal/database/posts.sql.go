@@ -11,40 +11,44 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
-const createPost = `-- name: CreatePost :one
-INSERT INTO posts (title, url, description, published_at, feed_id, created_at, updated_at)
-VALUES ($1,
-        $2,
-        $3,
-        $4,
-        $5,
-        $6,
-        $7)
-RETURNING id, title, url, description, published_at, feed_id, created_at, updated_at
+const adoptPostGuids = `-- name: AdoptPostGuids :exec
+UPDATE posts p
+SET guid = i.guid
+FROM (SELECT DISTINCT ON (guid) guid, url
+      FROM UNNEST($1::TEXT[], $2::TEXT[]) AS item(guid, url)
+      WHERE guid <> url
+      ORDER BY guid) AS i
+WHERE p.feed_id = $3
+  AND p.guid = p.url
+  AND p.url = i.url
+  AND NOT EXISTS (SELECT 1 FROM posts taken WHERE taken.feed_id = $3 AND taken.guid = i.guid)
 `
 
-type CreatePostParams struct {
-	Title       string
-	Url         sql.NullString
-	Description sql.NullString
-	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+type AdoptPostGuidsParams struct {
+	Guids  []string
+	Urls   []string
+	FeedID uuid.UUID
 }
 
-func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
-	row := q.db.QueryRowContext(ctx, createPost,
-		arg.Title,
-		arg.Url,
-		arg.Description,
-		arg.PublishedAt,
-		arg.FeedID,
-		arg.CreatedAt,
-		arg.UpdatedAt,
-	)
+// Posts stored before guids were kept got their URL as guid by the 008
+// migration. An item with a guid of its own and such a post's URL takes the
+// post over instead of being stored a second time.
+func (q *Queries) AdoptPostGuids(ctx context.Context, arg AdoptPostGuidsParams) error {
+	_, err := q.db.ExecContext(ctx, adoptPostGuids, pq.Array(arg.Guids), pq.Array(arg.Urls), arg.FeedID)
+	return err
+}
+
+const getPost = `-- name: GetPost :one
+SELECT id, title, url, description, published_at, feed_id, created_at, updated_at, guid, content, author, image_url, episode, original_url
+FROM posts
+WHERE id = $1
+`
+
+func (q *Queries) GetPost(ctx context.Context, id uuid.UUID) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPost, id)
 	var i Post
 	err := row.Scan(
 		&i.ID,
@@ -55,27 +59,189 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.FeedID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Guid,
+		&i.Content,
+		&i.Author,
+		&i.ImageUrl,
+		&i.Episode,
+		&i.OriginalUrl,
 	)
 	return i, err
 }
 
+const getPostCategories = `-- name: GetPostCategories :many
+SELECT post_id, name
+FROM post_categories
+WHERE post_id = ANY ($1::UUID[])
+ORDER BY post_id, name
+`
+
+func (q *Queries) GetPostCategories(ctx context.Context, postIds []uuid.UUID) ([]PostCategory, error) {
+	rows, err := q.db.QueryContext(ctx, getPostCategories, pq.Array(postIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PostCategory
+	for rows.Next() {
+		var i PostCategory
+		if err := rows.Scan(&i.PostID, &i.Name); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostEnclosures = `-- name: GetPostEnclosures :many
+SELECT post_id, url, mime_type, length, duration_seconds
+FROM post_enclosures
+WHERE post_id = ANY ($1::UUID[])
+ORDER BY post_id, url
+`
+
+func (q *Queries) GetPostEnclosures(ctx context.Context, postIds []uuid.UUID) ([]PostEnclosure, error) {
+	rows, err := q.db.QueryContext(ctx, getPostEnclosures, pq.Array(postIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PostEnclosure
+	for rows.Next() {
+		var i PostEnclosure
+		if err := rows.Scan(
+			&i.PostID,
+			&i.Url,
+			&i.MimeType,
+			&i.Length,
+			&i.DurationSeconds,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostGuidsWithEnclosures = `-- name: GetPostGuidsWithEnclosures :many
+SELECT p.guid
+FROM posts p
+WHERE p.feed_id = $1
+  AND p.guid = ANY ($2::TEXT[])
+  AND EXISTS (SELECT 1 FROM post_enclosures pe WHERE pe.post_id = p.id)
+`
+
+type GetPostGuidsWithEnclosuresParams struct {
+	FeedID uuid.UUID
+	Guids  []string
+}
+
+// Returns which of the given guids of a feed belong to posts that have
+// enclosures stored.
+func (q *Queries) GetPostGuidsWithEnclosures(ctx context.Context, arg GetPostGuidsWithEnclosuresParams) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getPostGuidsWithEnclosures, arg.FeedID, pq.Array(arg.Guids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var guid string
+		if err := rows.Scan(&guid); err != nil {
+			return nil, err
+		}
+		items = append(items, guid)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostRevisions = `-- name: GetPostRevisions :many
+SELECT id, post_id, title, url, description, published_at, created_at
+FROM post_revisions
+WHERE post_id = $1
+ORDER BY created_at DESC
+`
+
+func (q *Queries) GetPostRevisions(ctx context.Context, postID uuid.UUID) ([]PostRevision, error) {
+	rows, err := q.db.QueryContext(ctx, getPostRevisions, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PostRevision
+	for rows.Next() {
+		var i PostRevision
+		if err := rows.Scan(
+			&i.ID,
+			&i.PostID,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getPostsForUser = `-- name: GetPostsForUser :many
-SELECT p.id, p.title, p.url, p.description, p.published_at, p.feed_id, p.created_at, p.updated_at
+SELECT p.id, p.title, p.url, p.description, p.published_at, p.feed_id, p.created_at, p.updated_at, p.guid, p.content, p.author, p.image_url, p.episode, p.original_url
 FROM posts p
          JOIN feeds f ON f.id = p.feed_id
          JOIN feed_follows ff ON ff.feed_id = f.id
 WHERE ff.user_id = $1
+  AND ($2::TEXT IS NULL OR p.author ILIKE '%' || $2 || '%')
+  AND ($3::TEXT IS NULL OR EXISTS (SELECT 1
+                                                   FROM post_categories pc
+                                                   WHERE pc.post_id = p.id
+                                                     AND LOWER(pc.name) = LOWER($3)))
 ORDER BY p.published_at DESC NULLS LAST, p.created_at DESC
-LIMIT $2
+LIMIT $4
 `
 
 type GetPostsForUserParams struct {
-	UserID uuid.UUID
-	Limit  int32
+	UserID   uuid.UUID
+	Author   sql.NullString
+	Category sql.NullString
+	Limit    int32
 }
 
+// Returns the latest posts of the feeds the user follows, optionally only the
+// ones by an author matching author or tagged with category.
 func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]Post, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, getPostsForUser,
+		arg.UserID,
+		arg.Author,
+		arg.Category,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -92,6 +258,12 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.FeedID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Guid,
+			&i.Content,
+			&i.Author,
+			&i.ImageUrl,
+			&i.Episode,
+			&i.OriginalUrl,
 		); err != nil {
 			return nil, err
 		}
@@ -105,3 +277,233 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	}
 	return items, nil
 }
+
+const getRecentPostDates = `-- name: GetRecentPostDates :many
+SELECT published_at
+FROM posts
+WHERE feed_id = $1
+  AND published_at IS NOT NULL
+  AND published_at <> created_at
+ORDER BY published_at DESC
+LIMIT $2
+`
+
+type GetRecentPostDatesParams struct {
+	FeedID uuid.UUID
+	Limit  int32
+}
+
+// Returns the publish dates of the latest posts of a feed. Undated posts are
+// stored with their first-seen time, which says nothing about how often the
+// feed publishes, so they are left out.
+func (q *Queries) GetRecentPostDates(ctx context.Context, arg GetRecentPostDatesParams) ([]sql.NullTime, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentPostDates, arg.FeedID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []sql.NullTime
+	for rows.Next() {
+		var published_at sql.NullTime
+		if err := rows.Scan(&published_at); err != nil {
+			return nil, err
+		}
+		items = append(items, published_at)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setPostEnclosures = `-- name: SetPostEnclosures :exec
+WITH post AS (SELECT id
+              FROM posts
+              WHERE feed_id = $1
+                AND guid = $2),
+     dropped AS (
+         DELETE FROM post_enclosures
+             WHERE post_id = (SELECT id FROM post)
+                 AND url <> ALL ($3::TEXT[]))
+INSERT
+INTO post_enclosures (post_id, url, mime_type, length, duration_seconds)
+SELECT post.id, e.url, NULLIF(e.mime_type, ''), NULLIF(e.length, 0), NULLIF(e.duration_seconds, 0)
+FROM post,
+     UNNEST($3::TEXT[], $4::TEXT[], $5::BIGINT[], $6::INTEGER[])
+         AS e(url, mime_type, length, duration_seconds)
+ON CONFLICT (post_id, url) DO UPDATE
+    SET mime_type        = EXCLUDED.mime_type,
+        length           = EXCLUDED.length,
+        duration_seconds = EXCLUDED.duration_seconds
+    WHERE (post_enclosures.mime_type, post_enclosures.length, post_enclosures.duration_seconds)
+              IS DISTINCT FROM (EXCLUDED.mime_type, EXCLUDED.length, EXCLUDED.duration_seconds)
+`
+
+type SetPostEnclosuresParams struct {
+	FeedID    uuid.UUID
+	Guid      string
+	Urls      []string
+	MimeTypes []string
+	Lengths   []int64
+	Durations []int32
+}
+
+// Replaces the enclosures of the post with the given guid, the arrays hold
+// one value per enclosure and zero or empty values are stored as NULL.
+func (q *Queries) SetPostEnclosures(ctx context.Context, arg SetPostEnclosuresParams) error {
+	_, err := q.db.ExecContext(ctx, setPostEnclosures,
+		arg.FeedID,
+		arg.Guid,
+		pq.Array(arg.Urls),
+		pq.Array(arg.MimeTypes),
+		pq.Array(arg.Lengths),
+		pq.Array(arg.Durations),
+	)
+	return err
+}
+
+const upsertPost = `-- name: UpsertPost :one
+WITH previous AS (SELECT id, title, url, description, published_at, feed_id, created_at, updated_at, guid, content, author, image_url, episode, original_url
+                  FROM posts
+                  WHERE feed_id = $1
+                    AND guid = $2),
+     upserted AS (
+         INSERT INTO posts (title, url, original_url, description, content, author, image_url, episode, published_at,
+                            feed_id, guid, created_at, updated_at)
+             VALUES ($3,
+                     $4,
+                     $5,
+                     $6,
+                     $7,
+                     $8,
+                     $9,
+                     $10,
+                     COALESCE($11::TIMESTAMP, $12),
+                     $1,
+                     $2,
+                     $12,
+                     $13)
+             ON CONFLICT (feed_id, guid) DO UPDATE
+                 SET title = EXCLUDED.title,
+                     url = EXCLUDED.url,
+                     original_url = EXCLUDED.original_url,
+                     description = EXCLUDED.description,
+                     content = EXCLUDED.content,
+                     author = EXCLUDED.author,
+                     image_url = EXCLUDED.image_url,
+                     episode = EXCLUDED.episode,
+                     published_at = COALESCE($11::TIMESTAMP, posts.published_at),
+                     updated_at = EXCLUDED.updated_at
+                 WHERE posts.title IS DISTINCT FROM EXCLUDED.title
+                     OR posts.url IS DISTINCT FROM EXCLUDED.url
+                     OR posts.description IS DISTINCT FROM EXCLUDED.description
+                     OR posts.content IS DISTINCT FROM EXCLUDED.content
+                     OR posts.author IS DISTINCT FROM EXCLUDED.author
+                     OR posts.image_url IS DISTINCT FROM EXCLUDED.image_url
+                     OR posts.episode IS DISTINCT FROM EXCLUDED.episode
+                     OR posts.published_at IS DISTINCT FROM
+                        COALESCE($11::TIMESTAMP, posts.published_at)
+             RETURNING id, title, url, description, published_at, feed_id, created_at, updated_at, guid, content, author, image_url, episode, original_url),
+     revision AS (
+         INSERT INTO post_revisions (post_id, title, url, description, published_at, created_at)
+             SELECT p.id, p.title, COALESCE(p.original_url, p.url), p.description, p.published_at, u.updated_at
+             FROM previous p
+                      JOIN upserted u ON u.id = p.id
+             WHERE p.title IS DISTINCT FROM u.title
+                OR p.url IS DISTINCT FROM u.url
+                OR p.description IS DISTINCT FROM u.description
+                OR p.published_at IS DISTINCT FROM u.published_at),
+     dropped_categories AS (
+         DELETE FROM post_categories
+             WHERE post_id = (SELECT id FROM previous)
+                 AND name <> ALL ($14::TEXT[])),
+     added_categories AS (
+         INSERT INTO post_categories (post_id, name)
+             SELECT COALESCE((SELECT id FROM previous), (SELECT id FROM upserted)), UNNEST($14::TEXT[])
+             ON CONFLICT DO NOTHING)
+SELECT u.id, u.title, u.url, u.description, u.published_at, u.feed_id, u.created_at, u.updated_at, u.guid, u.content, u.author, u.image_url, u.episode, u.original_url, p.id IS NOT NULL AS updated
+FROM upserted u
+         LEFT JOIN previous p ON p.id = u.id
+`
+
+type UpsertPostParams struct {
+	FeedID      uuid.UUID
+	Guid        string
+	Title       string
+	Url         sql.NullString
+	OriginalUrl sql.NullString
+	Description sql.NullString
+	Content     sql.NullString
+	Author      sql.NullString
+	ImageUrl    sql.NullString
+	Episode     sql.NullInt32
+	PublishedAt sql.NullTime
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Categories  []string
+}
+
+type UpsertPostRow struct {
+	ID          uuid.UUID
+	Title       string
+	Url         sql.NullString
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Guid        string
+	Content     sql.NullString
+	Author      sql.NullString
+	ImageUrl    sql.NullString
+	Episode     sql.NullInt32
+	OriginalUrl sql.NullString
+	Updated     bool
+}
+
+// Inserts a new post or updates the stored one when the item changed, the
+// replaced version is kept in post_revisions when a field shown in the history
+// changed. url is the canonical link the post is compared by, a change of only
+// the original_url isn't stored. The categories of the post are replaced by the
+// given ones. Returns no rows when nothing but the categories changed. Items
+// without a publish date fall back to the first-seen time.
+func (q *Queries) UpsertPost(ctx context.Context, arg UpsertPostParams) (UpsertPostRow, error) {
+	row := q.db.QueryRowContext(ctx, upsertPost,
+		arg.FeedID,
+		arg.Guid,
+		arg.Title,
+		arg.Url,
+		arg.OriginalUrl,
+		arg.Description,
+		arg.Content,
+		arg.Author,
+		arg.ImageUrl,
+		arg.Episode,
+		arg.PublishedAt,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		pq.Array(arg.Categories),
+	)
+	var i UpsertPostRow
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Guid,
+		&i.Content,
+		&i.Author,
+		&i.ImageUrl,
+		&i.Episode,
+		&i.OriginalUrl,
+		&i.Updated,
+	)
+	return i, err
+}
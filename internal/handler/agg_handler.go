@@ -0,0 +1,637 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gator/internal/canonical"
+	"gator/internal/config"
+	"gator/internal/core"
+	"gator/internal/database"
+	"gator/internal/rss"
+	"gator/internal/schedule"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func AggregateFeeds(s *core.State, cmd core.Command) error {
+	if len(cmd.Args) < 1 {
+		return fmt.Errorf("the agg handler expects a single argument, the time interval how oftern to fetch feeds")
+	}
+
+	duration, err := time.ParseDuration(cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("failed parsing duration: %s", err)
+	}
+
+	fmt.Printf("Collecting up to %d due feeds every %s with %d workers\n", s.Config.Agg.BatchSize, duration, s.Config.Agg.Workers)
+
+	ticker := time.NewTicker(duration)
+	defer ticker.Stop()
+
+	for {
+		err := scrapeFeeds(s)
+		if err != nil {
+			fmt.Printf("failed scraping feeds: %s\n", err)
+		}
+
+		select {
+		case <-s.Ctx.Done():
+			fmt.Println("Stopped collecting feeds")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// scrapeResult is the outcome of scraping a single feed.
+type scrapeResult struct {
+	Feed        database.Feed
+	Saved       saveResult
+	NotModified bool
+	// Aborted is set when the fetch was canceled on shutdown, it doesn't
+	// count as a failure of the feed
+	Aborted bool
+	// Deferred is set when the fetch wasn't started to spare its host, the
+	// feed is fetched again in a later cycle
+	Deferred error
+	// TakenOver is set when the lease ran out while the feed waited for a
+	// worker and another agg process leased it in the meantime
+	TakenOver bool
+	// MovedTo is the URL the feed permanently moved to, Merged tells whether
+	// another feed already had that URL and the feed was merged into it
+	MovedTo string
+	Merged  bool
+	// Retired is set when the server said the feed is gone, PostponedUntil
+	// when it asked to retry later
+	Retired        bool
+	PostponedUntil time.Time
+	// Failures counts the failed fetches in a row when Err is set, Disabled
+	// tells whether that got the feed disabled
+	Failures int32
+	Disabled bool
+	Err      error
+}
+
+// scrapeFeeds leases the next batch of feeds, so concurrent agg processes
+// never fetch the same feed, and fetches them with a bounded pool of workers
+// and prints a line per feed followed by a summary of the cycle.
+//
+// Once the root context is canceled no further feeds are started and their
+// leases are released, in-flight fetches get the configured grace period to
+// finish before they are aborted.
+func scrapeFeeds(s *core.State) error {
+	started := time.Now()
+
+	feeds, err := s.Db.LeaseFeedsToFetch(s.Ctx, database.LeaseFeedsToFetchParams{
+		LeaseSeconds: int32(time.Duration(s.Config.Agg.LeaseDuration).Seconds()),
+		BatchSize:    int32(s.Config.Agg.BatchSize),
+	})
+	if err != nil {
+		return fmt.Errorf("failed leasing feeds to fetch: %s", err)
+	}
+	if len(feeds) == 0 {
+		fmt.Println("No feeds to fetch")
+		return nil
+	}
+
+	workCtx, cancelWork := context.WithCancel(context.WithoutCancel(s.Ctx))
+	defer cancelWork()
+	go func() {
+		select {
+		case <-s.Ctx.Done():
+			grace := time.Duration(s.Config.Agg.ShutdownGrace)
+			fmt.Printf("Shutting down, waiting up to %s for in-flight fetches\n", grace)
+			select {
+			case <-time.After(grace):
+				cancelWork()
+			case <-workCtx.Done():
+			}
+		case <-workCtx.Done():
+		}
+	}()
+
+	jobs := make(chan database.Feed)
+	results := make(chan scrapeResult)
+
+	var wg sync.WaitGroup
+	for range min(s.Config.Agg.Workers, len(feeds)) {
+		wg.Go(func() {
+			for feed := range jobs {
+				results <- scrapeFeed(workCtx, s, feed)
+			}
+		})
+	}
+
+	var skipped int
+	go func() {
+		for i, feed := range feeds {
+			select {
+			case jobs <- feed:
+				continue
+			case <-s.Ctx.Done():
+			}
+			skipped = len(feeds) - i
+			releaseLeases(s, feeds[i:])
+			break
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	var saved saveResult
+	var notModified, failed, aborted, deferred, takenOver int
+	for result := range results {
+		if result.MovedTo != "" {
+			fmt.Printf("Feed %s moved to %s\n", result.Feed.Name, result.MovedTo)
+		}
+
+		switch {
+		case result.Aborted:
+			aborted++
+			fmt.Printf("Aborted feed %s on shutdown\n", result.Feed.Name)
+		case result.TakenOver:
+			takenOver++
+			fmt.Printf("Skipped feed %s, its lease ran out and another agg process took it over\n", result.Feed.Name)
+		case result.Deferred != nil:
+			deferred++
+			fmt.Printf("Deferred feed %s: %s\n", result.Feed.Name, result.Deferred)
+		case result.Err != nil:
+			failed++
+			fmt.Printf("Failed feed %s: %s\n", result.Feed.Name, result.Err)
+			if result.Disabled {
+				fmt.Printf("Disabled feed %s after %d failures in a row, see gator feeds --broken\n", result.Feed.Name, result.Failures)
+			}
+		case result.Retired:
+			fmt.Printf("Retired feed %s, the server says it's gone\n", result.Feed.Name)
+		case !result.PostponedUntil.IsZero():
+			fmt.Printf("Postponed feed %s until %s as asked by the server\n", result.Feed.Name, result.PostponedUntil.Format(time.DateTime))
+		case result.Merged:
+			fmt.Printf("Merged feed %s into the feed already at %s\n", result.Feed.Name, result.MovedTo)
+		case result.NotModified:
+			notModified++
+			fmt.Printf("Feed %s not modified since last fetch\n", result.Feed.Name)
+		default:
+			saved.Created += result.Saved.Created
+			saved.Updated += result.Saved.Updated
+			fmt.Printf("Saved %d new and %d updated posts for feed: %s\n", result.Saved.Created, result.Saved.Updated, result.Feed.Name)
+		}
+	}
+
+	fmt.Printf("Fetched %d feeds in %s: %d new posts, %d updated, %d not modified, %d failed, %d deferred\n",
+		len(feeds)-skipped-aborted-deferred-takenOver, time.Since(started).Round(time.Millisecond), saved.Created, saved.Updated, notModified, failed, deferred)
+	if takenOver > 0 {
+		fmt.Printf("%d feeds were taken over by another agg process, raise lease_duration above the time a batch takes\n", takenOver)
+	}
+	if skipped > 0 || aborted > 0 {
+		fmt.Printf("Shutdown left %d feeds unfetched and aborted %d in-flight fetches\n", skipped, aborted)
+	}
+
+	return nil
+}
+
+// detached returns a context that outlives the cancellation of ctx, for the
+// bookkeeping that still has to happen after a fetch timed out or was aborted.
+func detached(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+}
+
+// releaseLeases hands feeds that were leased but not fetched back to the
+// other agg processes.
+func releaseLeases(s *core.State, feeds []database.Feed) {
+	ctx, cancel := detached(s.Ctx)
+	defer cancel()
+
+	for _, feed := range feeds {
+		if err := s.Db.ReleaseFeedLease(ctx, feed.ID); err != nil {
+			fmt.Printf("failed releasing lease of feed %s: %s\n", feed.Name, err)
+		}
+	}
+}
+
+// scrapeFeed fetches a single leased feed and stores its items, bounded by
+// the configured fetch timeout. The outcome is recorded on the feed and the
+// lease is released once it's done.
+func scrapeFeed(ctx context.Context, s *core.State, dbFeed database.Feed) (result scrapeResult) {
+	result = scrapeResult{Feed: dbFeed}
+
+	// the whole batch is leased up front, feeds queued behind the workers get
+	// a fresh lease once their fetch starts
+	renewed, err := s.Db.RenewFeedLease(ctx, database.RenewFeedLeaseParams{
+		LeaseSeconds: int32(time.Duration(s.Config.Agg.LeaseDuration).Seconds()),
+		ID:           dbFeed.ID,
+		LeasedUntil:  dbFeed.LeaseExpiresAt,
+	})
+	if err != nil {
+		result.Err = fmt.Errorf("failed renewing feed lease: %s", err)
+		return result
+	}
+	if renewed == 0 {
+		result.TakenOver = true
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(s.Config.Agg.FetchTimeout))
+	defer cancel()
+
+	defer func() {
+		cleanupCtx, cancel := detached(ctx)
+		defer cancel()
+
+		if result.Aborted || result.Deferred != nil {
+			if err := s.Db.ReleaseFeedLease(cleanupCtx, dbFeed.ID); err != nil {
+				fmt.Printf("failed releasing lease of feed %s: %s\n", dbFeed.Name, err)
+			}
+			return
+		}
+		if _, err := s.Db.MarkFeedFetched(cleanupCtx, dbFeed.ID); err != nil && result.Err == nil {
+			result.Err = fmt.Errorf("failed releasing feed lease: %s", err)
+		}
+	}()
+
+	feed, err := s.Fetcher.FetchFeed(ctx, dbFeed.OriginalUrl, rss.Validators{
+		ETag:         dbFeed.Etag.String,
+		LastModified: dbFeed.LastModified.String,
+	})
+	if errors.Is(ctx.Err(), context.Canceled) {
+		result.Aborted = true
+		return result
+	}
+	var deferredErr *rss.DeferredError
+	if errors.As(err, &deferredErr) {
+		result.Deferred = deferredErr
+		return result
+	}
+	if feed != nil && feed.MovedTo != "" {
+		result.MovedTo = feed.MovedTo
+		result.Merged, result.Err = moveFeed(ctx, s, dbFeed, feed.MovedTo)
+		if result.Merged || result.Err != nil {
+			return result
+		}
+	}
+	if errors.Is(err, rss.ErrNotModified) {
+		result.NotModified = true
+		result.Err = recordSuccess(ctx, s, dbFeed.ID, http.StatusNotModified, nil)
+		return result
+	}
+	var statusErr *rss.StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusGone {
+		result.Retired = true
+		result.Err = retireFeed(ctx, s, dbFeed, statusErr)
+		return result
+	}
+	if errors.As(err, &statusErr) && !statusErr.RetryAfter.IsZero() &&
+		(statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode == http.StatusServiceUnavailable) {
+		result.PostponedUntil, result.Err = postponeFeed(ctx, s, dbFeed, statusErr)
+		return result
+	}
+	if err != nil {
+		result.Err = fmt.Errorf("failed fetching feed: %s", err)
+		failure, recordErr := recordFailure(ctx, s, dbFeed, err)
+		if recordErr != nil {
+			result.Err = fmt.Errorf("%s, failed recording it: %s", result.Err, recordErr)
+			return result
+		}
+		result.Failures = failure.ConsecutiveFailures
+		result.Disabled = failure.DisabledAt.Valid
+		return result
+	}
+
+	result.Saved, result.Err = saveFeed(ctx, s, dbFeed.ID, feed)
+	if errors.Is(ctx.Err(), context.Canceled) {
+		result.Aborted = true
+		return result
+	}
+	if result.Err != nil {
+		return result
+	}
+	result.Err = recordSuccess(ctx, s, dbFeed.ID, feed.StatusCode, feed)
+	return result
+}
+
+func recordSuccess(ctx context.Context, s *core.State, feedID uuid.UUID, status int, feed *rss.Feed) error {
+	ctx, cancel := detached(ctx)
+	defer cancel()
+
+	next, err := nextFetch(ctx, s, feedID, feed)
+	if err != nil {
+		return err
+	}
+
+	err = s.Db.RecordFeedSuccess(ctx, database.RecordFeedSuccessParams{
+		LastStatus:       sql.NullInt32{Int32: int32(status), Valid: true},
+		NextFetchSeconds: int32(time.Until(next).Seconds()),
+		ID:               feedID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed recording fetch: %s", err)
+	}
+	return nil
+}
+
+// nextFetch schedules the feed from how often it publishes and the caching
+// hints of the channel and the HTTP response.
+func nextFetch(ctx context.Context, s *core.State, feedID uuid.UUID, feed *rss.Feed) (time.Time, error) {
+	dates, err := s.Db.GetRecentPostDates(ctx, database.GetRecentPostDatesParams{
+		FeedID: feedID,
+		Limit:  20,
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed getting recent post dates: %s", err)
+	}
+
+	var in schedule.Input
+	for _, date := range dates {
+		in.PostDates = append(in.PostDates, date.Time)
+	}
+	if feed != nil {
+		in.TTL = feed.Channel.CacheTTL()
+		in.Expires = feed.Expires
+		in.SkipHours = feed.Channel.SkippedHours()
+		in.SkipDays = feed.Channel.SkippedDays()
+	}
+
+	agg := s.Config.Agg
+	return schedule.Next(time.Now(), in, time.Duration(agg.MinInterval), time.Duration(agg.MaxInterval)), nil
+}
+
+// recordFailure stores the failed fetch on the feed and pushes its next fetch
+// back exponentially, the feed gets disabled after too many failures in a row.
+func recordFailure(ctx context.Context, s *core.State, dbFeed database.Feed, fetchErr error) (database.RecordFeedFailureRow, error) {
+	ctx, cancel := detached(ctx)
+	defer cancel()
+
+	var status sql.NullInt32
+	var statusErr *rss.StatusError
+	if errors.As(fetchErr, &statusErr) {
+		status = sql.NullInt32{Int32: int32(statusErr.StatusCode), Valid: true}
+	}
+
+	return s.Db.RecordFeedFailure(ctx, database.RecordFeedFailureParams{
+		LastError:      sql.NullString{String: fetchErr.Error(), Valid: true},
+		LastStatus:     status,
+		BackoffSeconds: int32(backoff(s.Config.Agg, dbFeed.ConsecutiveFailures).Seconds()),
+		MaxFailures:    int32(s.Config.Agg.MaxFailures),
+		ID:             dbFeed.ID,
+	})
+}
+
+// moveFeed points the feed to the URL it permanently moved to and keeps the
+// redirect in its history. When another feed already has that URL, compared
+// in its canonical form, the feed is merged into it instead and true is
+// returned.
+func moveFeed(ctx context.Context, s *core.State, dbFeed database.Feed, movedTo string) (bool, error) {
+	ctx, cancel := detached(ctx)
+	defer cancel()
+
+	err := s.Db.RecordFeedRedirect(ctx, database.RecordFeedRedirectParams{
+		FeedID:  dbFeed.ID,
+		FromUrl: dbFeed.OriginalUrl,
+		ToUrl:   movedTo,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed recording redirect: %s", err)
+	}
+
+	existing, err := s.Db.GetFeedByUrl(ctx, canonical.URL(movedTo))
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && existing.ID == dbFeed.ID) {
+		err := s.Db.UpdateFeedUrl(ctx, database.UpdateFeedUrlParams{
+			ID:          dbFeed.ID,
+			Url:         canonical.URL(movedTo),
+			OriginalUrl: movedTo,
+		})
+		if err != nil {
+			return false, fmt.Errorf("failed updating feed url: %s", err)
+		}
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed getting feed by url: %s", err)
+	}
+
+	err = s.Db.MergeFeed(ctx, database.MergeFeedParams{
+		IntoID:    existing.ID,
+		FromID:    dbFeed.ID,
+		LastError: sql.NullString{String: fmt.Sprintf("moved to %s and merged into %s", movedTo, existing.Name), Valid: true},
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed merging feed into %s: %s", existing.Name, err)
+	}
+	return true, nil
+}
+
+// retireFeed disables the feed for good after the server answered 410 Gone.
+func retireFeed(ctx context.Context, s *core.State, dbFeed database.Feed, statusErr *rss.StatusError) error {
+	ctx, cancel := detached(ctx)
+	defer cancel()
+
+	err := s.Db.RetireFeed(ctx, database.RetireFeedParams{
+		LastError:  sql.NullString{String: statusErr.Error(), Valid: true},
+		LastStatus: sql.NullInt32{Int32: int32(statusErr.StatusCode), Valid: true},
+		ID:         dbFeed.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed retiring feed: %s", err)
+	}
+	return nil
+}
+
+// postponeFeed schedules the next fetch for when the server asked to be
+// retried, at most BackoffMax from now.
+func postponeFeed(ctx context.Context, s *core.State, dbFeed database.Feed, statusErr *rss.StatusError) (time.Time, error) {
+	ctx, cancel := detached(ctx)
+	defer cancel()
+
+	wait := min(max(time.Until(statusErr.RetryAfter), 0), time.Duration(s.Config.Agg.BackoffMax))
+	err := s.Db.PostponeFeed(ctx, database.PostponeFeedParams{
+		LastError:    sql.NullString{String: statusErr.Error(), Valid: true},
+		LastStatus:   sql.NullInt32{Int32: int32(statusErr.StatusCode), Valid: true},
+		RetrySeconds: int32(wait.Seconds()),
+		ID:           dbFeed.ID,
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed postponing feed: %s", err)
+	}
+	return time.Now().Add(wait), nil
+}
+
+// backoff returns how long to wait before fetching a feed again after it
+// failed, doubling the base wait for every earlier failure in a row.
+func backoff(agg config.AggConfig, failures int32) time.Duration {
+	wait, limit := time.Duration(agg.BackoffBase), time.Duration(agg.BackoffMax)
+	for range failures {
+		wait *= 2
+		if wait >= limit {
+			return limit
+		}
+	}
+	return min(wait, limit)
+}
+
+// saveResult counts the posts written by saveFeed.
+type saveResult struct {
+	Created int
+	Updated int
+}
+
+// saveFeed upserts the items of a fetched feed as posts, followed by the
+// feed metadata and cache validators.
+func saveFeed(ctx context.Context, s *core.State, feedID uuid.UUID, feed *rss.Feed) (saveResult, error) {
+	var result saveResult
+	if err := adoptPostGuids(ctx, s, feedID, feed.Channel.Item); err != nil {
+		return result, fmt.Errorf("failed adopting posts stored by URL: %s", err)
+	}
+	stored, err := storedEnclosures(ctx, s, feedID, feed.Channel.Item)
+	if err != nil {
+		return result, fmt.Errorf("failed getting stored enclosures: %s", err)
+	}
+
+	for _, item := range feed.Channel.Item {
+		publishedAt, ok := item.PublishedAt()
+		// a nil slice would be NULL and keep the stored categories
+		categories := item.Categories
+		if categories == nil {
+			categories = []string{}
+		}
+		episode, hasEpisode := item.Episode()
+		// guids that are links are compared in their canonical form like
+		// the links themselves
+		guid := canonical.URL(item.Identity())
+		post, err := s.Db.UpsertPost(ctx, database.UpsertPostParams{
+			FeedID:      feedID,
+			Guid:        guid,
+			Title:       item.Title,
+			Url:         nullString(canonical.URL(item.Link)),
+			OriginalUrl: nullString(item.Link),
+			Description: sql.NullString{String: item.Description, Valid: item.Description != ""},
+			Content:     sql.NullString{String: item.Content, Valid: item.Content != ""},
+			Author:      nullString(item.Author),
+			ImageUrl:    nullString(item.ImageURL()),
+			Episode:     sql.NullInt32{Int32: int32(episode), Valid: hasEpisode},
+			PublishedAt: sql.NullTime{Time: publishedAt, Valid: ok},
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
+			Categories:  categories,
+		})
+		// no rows means the stored post is up to date
+		changed := err == nil
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			fmt.Printf("failed saving post with title %s: %s\n", item.Title, err)
+			continue
+		}
+		if changed && post.Updated {
+			result.Updated++
+		} else if changed {
+			result.Created++
+		}
+
+		// unchanged posts only get their enclosures when none are stored
+		// yet, which fills them in for posts stored before they were kept
+		media := item.Media()
+		if changed || (len(media) > 0 && !stored[guid]) {
+			if err := saveEnclosures(ctx, s, feedID, guid, media); err != nil {
+				fmt.Printf("failed saving enclosures of post with title %s: %s\n", item.Title, err)
+			}
+		}
+	}
+
+	channel := feed.Channel
+	err = s.Db.UpdateFeedMetadata(ctx, database.UpdateFeedMetadataParams{
+		ID:          feedID,
+		Title:       nullString(channel.Title),
+		Description: nullString(channel.Description),
+		SiteUrl:     nullString(channel.Link),
+		Language:    nullString(channel.Language),
+		ImageUrl:    nullString(channel.ImageURL()),
+		Generator:   nullString(channel.Generator),
+	})
+	if err != nil {
+		return result, fmt.Errorf("failed storing feed metadata: %s", err)
+	}
+
+	// validators are stored last so a failed run doesn't turn into a 304 next time
+	err = s.Db.UpdateFeedCacheValidators(ctx, database.UpdateFeedCacheValidatorsParams{
+		ID:           feedID,
+		Etag:         sql.NullString{String: feed.ETag, Valid: feed.ETag != ""},
+		LastModified: sql.NullString{String: feed.LastModified, Valid: feed.LastModified != ""},
+	})
+	if err != nil {
+		return result, fmt.Errorf("failed storing cache validators: %s", err)
+	}
+
+	return result, nil
+}
+
+// adoptPostGuids hands the guids of the items to the posts stored by their
+// link before guids were kept, so they are updated rather than duplicated.
+func adoptPostGuids(ctx context.Context, s *core.State, feedID uuid.UUID, items []rss.Item) error {
+	params := database.AdoptPostGuidsParams{FeedID: feedID}
+	for _, item := range items {
+		guid := canonical.URL(item.Identity())
+		link := canonical.URL(item.Link)
+		if link != "" && guid != link {
+			params.Guids = append(params.Guids, guid)
+			params.Urls = append(params.Urls, link)
+		}
+	}
+	if len(params.Guids) == 0 {
+		return nil
+	}
+	return s.Db.AdoptPostGuids(ctx, params)
+}
+
+// storedEnclosures returns the guids of the items with media whose posts
+// already have enclosures stored.
+func storedEnclosures(ctx context.Context, s *core.State, feedID uuid.UUID, items []rss.Item) (map[string]bool, error) {
+	var guids []string
+	for _, item := range items {
+		if len(item.Media()) > 0 {
+			guids = append(guids, canonical.URL(item.Identity()))
+		}
+	}
+	stored := make(map[string]bool)
+	if len(guids) == 0 {
+		return stored, nil
+	}
+	found, err := s.Db.GetPostGuidsWithEnclosures(ctx, database.GetPostGuidsWithEnclosuresParams{
+		FeedID: feedID,
+		Guids:  guids,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, guid := range found {
+		stored[guid] = true
+	}
+	return stored, nil
+}
+
+func saveEnclosures(ctx context.Context, s *core.State, feedID uuid.UUID, guid string, media []rss.Enclosure) error {
+	params := database.SetPostEnclosuresParams{
+		FeedID: feedID,
+		Guid:   guid,
+		// empty slices rather than nil ones, which would be NULL
+		Urls:      make([]string, 0, len(media)),
+		MimeTypes: make([]string, 0, len(media)),
+		Lengths:   make([]int64, 0, len(media)),
+		Durations: make([]int32, 0, len(media)),
+	}
+	for _, enclosure := range media {
+		params.Urls = append(params.Urls, enclosure.URL)
+		params.MimeTypes = append(params.MimeTypes, enclosure.Type)
+		params.Lengths = append(params.Lengths, enclosure.Length)
+		params.Durations = append(params.Durations, int32(enclosure.Duration.Seconds()))
+	}
+	return s.Db.SetPostEnclosures(ctx, params)
+}
+
+func nullString(value string) sql.NullString {
+	value = strings.TrimSpace(value)
+	return sql.NullString{String: value, Valid: value != ""}
+}
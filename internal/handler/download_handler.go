@@ -0,0 +1,319 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gator/internal/canonical"
+	"gator/internal/config"
+	"gator/internal/core"
+	"gator/internal/database"
+	"hash"
+	"io"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Download fetches the enclosures of the newest posts of the followed feeds
+// into the download directory and removes the files of posts that dropped
+// out of the retention: download [--verify]
+//
+// Completed files are skipped, with --verify only once their checksum still
+// matches. Interrupted downloads resume where they stopped.
+func Download(s *core.State, cmd core.Command, currentUser database.User) error {
+	verify := false
+	for _, arg := range cmd.Args {
+		switch arg {
+		case "--verify":
+			verify = true
+		default:
+			return fmt.Errorf("unknown argument %s, the download handler only accepts --verify", arg)
+		}
+	}
+
+	downloads := s.Config.Download
+	maxKeep := downloads.Keep
+	for _, keep := range downloads.KeepPerFeed {
+		maxKeep = max(maxKeep, keep)
+	}
+
+	candidates, err := s.Db.GetDownloadCandidates(s.Ctx, database.GetDownloadCandidatesParams{
+		UserID:  currentUser.ID,
+		MaxKeep: int32(maxKeep),
+	})
+	if err != nil {
+		return fmt.Errorf("failed getting downloads: %s", err)
+	}
+
+	var downloaded, skipped, removed, failed int
+	for _, candidate := range candidates {
+		if s.Ctx.Err() != nil {
+			break
+		}
+
+		if int(candidate.Position) > keepFor(downloads, candidate.FeedUrl) {
+			if !candidate.DownloadID.Valid || candidate.State.String == "removed" {
+				continue
+			}
+			if err := removeDownload(s, candidate); err != nil {
+				fmt.Printf("failed removing %s: %s\n", candidate.Path.String, err)
+				failed++
+				continue
+			}
+			fmt.Printf("Removed %s\n", candidate.Path.String)
+			removed++
+			continue
+		}
+
+		if candidate.State.String == "complete" {
+			ok, err := checkDownload(candidate, verify)
+			if err != nil {
+				fmt.Printf("failed checking %s: %s\n", candidate.Path.String, err)
+			}
+			if ok {
+				skipped++
+				continue
+			}
+			fmt.Printf("Downloading %s again, the file is missing or changed\n", candidate.Path.String)
+		}
+
+		target := candidate.Path.String
+		if !candidate.Path.Valid {
+			target = downloadPath(downloads.Dir, candidate)
+		}
+		downloadID, err := s.Db.StartDownload(s.Ctx, database.StartDownloadParams{
+			PostID: candidate.PostID,
+			Url:    candidate.Url,
+			Path:   target,
+		})
+		if err != nil {
+			return fmt.Errorf("failed starting download of %s: %s", candidate.Url, err)
+		}
+
+		// a completed file that's downloaded again starts from scratch
+		if candidate.State.String == "complete" {
+			_ = os.Remove(target + ".part")
+		}
+
+		size, sum, err := downloadFile(s, candidate.Url, target)
+		if err != nil {
+			// the state is stored even when the download was interrupted by
+			// a shutdown, so the next run resumes it
+			ctx, cancel := detached(s.Ctx)
+			failErr := s.Db.FailDownload(ctx, database.FailDownloadParams{
+				ID:        downloadID,
+				Bytes:     size,
+				LastError: sql.NullString{String: err.Error(), Valid: true},
+			})
+			cancel()
+			if failErr != nil {
+				return fmt.Errorf("failed recording download failure of %s: %s", candidate.Url, failErr)
+			}
+			fmt.Printf("failed downloading %s: %s\n", candidate.Url, err)
+			failed++
+			continue
+		}
+
+		err = s.Db.CompleteDownload(s.Ctx, database.CompleteDownloadParams{
+			ID:     downloadID,
+			Bytes:  size,
+			Sha256: sql.NullString{String: sum, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed completing download of %s: %s", candidate.Url, err)
+		}
+		fmt.Printf("Downloaded %s (%d bytes)\n", target, size)
+		downloaded++
+	}
+
+	if s.Ctx.Err() != nil {
+		fmt.Println("Stopped downloading, partial files are resumed on the next run")
+	}
+	fmt.Printf("Downloaded %d, skipped %d, removed %d, failed %d\n", downloaded, skipped, removed, failed)
+	return nil
+}
+
+// keepFor returns the number of newest posts with enclosures kept for the
+// feed with the canonical URL feedURL.
+func keepFor(downloads config.DownloadConfig, feedURL string) int {
+	for configured, keep := range downloads.KeepPerFeed {
+		if canonical.URL(configured) == feedURL && keep > 0 {
+			return keep
+		}
+	}
+	return downloads.Keep
+}
+
+// removeDownload deletes the file of a download, complete or partial, and
+// marks it removed.
+func removeDownload(s *core.State, candidate database.GetDownloadCandidatesRow) error {
+	for _, name := range []string{candidate.Path.String, candidate.Path.String + ".part"} {
+		if err := os.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return s.Db.RemoveDownload(s.Ctx, candidate.DownloadID.UUID)
+}
+
+// checkDownload tells whether a completed download is still on disk with
+// its size and, when verify is set, its checksum.
+func checkDownload(candidate database.GetDownloadCandidatesRow, verify bool) (bool, error) {
+	info, err := os.Stat(candidate.Path.String)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if info.Size() != candidate.Bytes.Int64 {
+		return false, nil
+	}
+	if !verify {
+		return true, nil
+	}
+
+	file, err := os.Open(candidate.Path.String)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return false, err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)) == candidate.Sha256.String, nil
+}
+
+// downloadFile downloads fileURL to target through a .part file next to it,
+// resuming a part left by an earlier run. It returns the size of the part or
+// file and, once complete, its SHA-256.
+func downloadFile(s *core.State, fileURL, target string) (int64, string, error) {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return 0, "", err
+	}
+	part := target + ".part"
+	file, err := os.OpenFile(part, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+
+	// the checksum covers the whole file, so the part already on disk is
+	// hashed before the rest is appended
+	hasher := sha256.New()
+	offset, err := io.Copy(hasher, file)
+	if err != nil {
+		return 0, "", err
+	}
+
+	written := &countingWriter{count: offset}
+	var total int64
+	err = s.Fetcher.Download(s.Ctx, fileURL, offset, func(start, size int64) (io.Writer, error) {
+		total = size
+		if start == 0 && offset > 0 {
+			if err := restart(file, hasher); err != nil {
+				return nil, err
+			}
+			written.count = 0
+		} else if start != offset {
+			return nil, fmt.Errorf("server resumed at byte %d instead of %d", start, offset)
+		}
+		return io.MultiWriter(file, hasher, written), nil
+	})
+	if err != nil {
+		return written.count, "", err
+	}
+	if total > 0 && written.count != total {
+		return written.count, "", fmt.Errorf("download ended after %d of %d bytes", written.count, total)
+	}
+
+	if err := file.Close(); err != nil {
+		return written.count, "", err
+	}
+	if err := os.Rename(part, target); err != nil {
+		return written.count, "", err
+	}
+	return written.count, hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// restart empties a part file when the server sent the whole file instead of
+// the requested range.
+func restart(file *os.File, hasher hash.Hash) error {
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	hasher.Reset()
+	return nil
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	count int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.count += int64(len(p))
+	return len(p), nil
+}
+
+// downloadPath names the file of an enclosure after its post, in a directory
+// per feed: <dir>/<feed>/<date>-<title>-<hash of the URL><extension>. The
+// hash keeps several enclosures of a post apart.
+func downloadPath(dir string, candidate database.GetDownloadCandidatesRow) string {
+	date := "undated"
+	if candidate.PublishedAt.Valid {
+		date = candidate.PublishedAt.Time.Format("2006-01-02")
+	}
+	sum := sha256.Sum256([]byte(candidate.Url))
+	name := fmt.Sprintf("%s-%s-%s%s", date, slug(candidate.Title, "post"), hex.EncodeToString(sum[:3]), extension(candidate.Url, candidate.MimeType.String))
+	return filepath.Join(dir, slug(candidate.FeedName, candidate.FeedID.String()), name)
+}
+
+// slug turns a title into a short file name of lowercase letters, digits and
+// dashes, or returns fallback when nothing is left.
+func slug(title, fallback string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+			dash = false
+		case !dash && b.Len() > 0:
+			b.WriteByte('-')
+			dash = true
+		}
+		if b.Len() >= 60 {
+			break
+		}
+	}
+	name := strings.TrimRight(b.String(), "-")
+	if name == "" {
+		return fallback
+	}
+	return name
+}
+
+// extension returns the file extension of an enclosure, taken from its URL
+// or else its MIME type.
+func extension(fileURL, mimeType string) string {
+	if parsed, err := url.Parse(fileURL); err == nil {
+		ext := strings.ToLower(path.Ext(parsed.Path))
+		if len(ext) > 1 && len(ext) <= 6 {
+			return ext
+		}
+	}
+	if exts, err := mime.ExtensionsByType(mimeType); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ".bin"
+}
@@ -1,38 +1,85 @@
 package handler
 
 import (
+	"bufio"
 	"context"
 	"database/sql"
-	"errors"
 	"fmt"
+	"gator/internal/canonical"
 	"gator/internal/core"
 	"gator/internal/database"
 	"gator/internal/rss"
+	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/lib/pq"
 )
 
 func AddFeed(s *core.State, cmd core.Command, currentUser database.User) error {
-	if len(cmd.Args) < 2 {
-		return fmt.Errorf("the addfeed handler expects a two params, name and url")
+	if len(cmd.Args) < 1 {
+		return fmt.Errorf("the addfeed handler expects the feed url and optionally a name: addfeed [name] <url>")
 	}
 
-	feed, err := s.Db.CreateFeed(context.Background(), database.CreateFeedParams{
-		ID:        uuid.New(),
-		UserID:    currentUser.ID,
-		Name:      cmd.Args[0],
-		Url:       cmd.Args[1],
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+	name, pageURL := "", cmd.Args[0]
+	if len(cmd.Args) > 1 {
+		name, pageURL = cmd.Args[0], cmd.Args[1]
+	}
+
+	candidate, err := discoverFeed(s.Ctx, s.Fetcher, pageURL)
+	if err != nil {
+		return err
+	}
+	feedURL := candidate.URL
+
+	// the same feed may be given as another variant of its URL
+	canonicalURL := canonical.URL(feedURL)
+	if existing, err := s.Db.GetFeedByUrl(s.Ctx, canonicalURL); err == nil {
+		return fmt.Errorf("feed %s was already added as %s, follow it with: gator follow %s", feedURL, existing.Name, existing.OriginalUrl)
+	}
+
+	// feeds downloaded while discovering them aren't fetched again
+	parsedFeed := candidate.Feed
+	if parsedFeed == nil {
+		parsedFeed, err = s.Fetcher.FetchFeed(s.Ctx, feedURL, rss.Validators{})
+		if err != nil {
+			return fmt.Errorf("%s is not a readable feed: %s", feedURL, err)
+		}
+	}
+	if name == "" {
+		name = strings.TrimSpace(parsedFeed.Channel.Title)
+	}
+	if name == "" {
+		name = feedURL
+	}
+
+	// the feed is only kept when it could be followed and seeded with its
+	// posts, so a failed addfeed can simply be retried
+	tx, err := s.DbConn.BeginTx(s.Ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed starting transaction: %s", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	txState := *s
+	txState.Db = s.Db.WithTx(tx)
+
+	feed, err := txState.Db.CreateFeed(s.Ctx, database.CreateFeedParams{
+		ID:          uuid.New(),
+		UserID:      currentUser.ID,
+		Name:        name,
+		Url:         canonicalURL,
+		OriginalUrl: feedURL,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
 	})
 	if err != nil {
 		return fmt.Errorf("failed creating feed: %s\n", err)
 	}
 
-	_, err = s.Db.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
+	_, err = txState.Db.CreateFeedFollow(s.Ctx, database.CreateFeedFollowParams{
 		ID:        uuid.New(),
 		FeedID:    feed.ID,
 		UserID:    currentUser.ID,
@@ -43,24 +90,69 @@ func AddFeed(s *core.State, cmd core.Command, currentUser database.User) error {
 		return fmt.Errorf("failed creating feed follow: %s\n", err)
 	}
 
+	result, err := saveFeed(s.Ctx, &txState, feed.ID, parsedFeed)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed saving feed: %s", err)
+	}
+
 	fmt.Printf("Name: %s\n", feed.Name)
-	fmt.Printf("URL: %s\n", feed.Url)
+	fmt.Printf("URL: %s\n", feed.OriginalUrl)
 	fmt.Printf("User ID: %s\n", feed.UserID)
+	fmt.Printf("Posts: %d\n", result.Created)
 
 	return nil
 }
 
+// discoverFeed resolves the given URL to a feed, letting the user pick when
+// a site advertises more than one feed.
+func discoverFeed(ctx context.Context, fetcher *rss.Fetcher, pageURL string) (rss.Candidate, error) {
+	candidates, err := fetcher.Discover(ctx, pageURL)
+	if err != nil {
+		return rss.Candidate{}, fmt.Errorf("failed discovering feed at %s: %s", pageURL, err)
+	}
+	if len(candidates) == 0 {
+		return rss.Candidate{}, fmt.Errorf("no feed found at %s", pageURL)
+	}
+	if len(candidates) == 1 {
+		if candidates[0].URL != pageURL {
+			fmt.Printf("Found feed at %s\n", candidates[0].URL)
+		}
+		return candidates[0], nil
+	}
+
+	fmt.Printf("Found %d feeds at %s:\n", len(candidates), pageURL)
+	for i, candidate := range candidates {
+		fmt.Printf("  %d) %s %s\n", i+1, candidate.URL, candidate.Title)
+	}
+	fmt.Print("Pick a feed: ")
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return rss.Candidate{}, fmt.Errorf("failed reading choice: %s", err)
+	}
+	choice, err := strconv.Atoi(strings.TrimSpace(answer))
+	if err != nil || choice < 1 || choice > len(candidates) {
+		return rss.Candidate{}, fmt.Errorf("invalid choice %q", strings.TrimSpace(answer))
+	}
+
+	return candidates[choice-1], nil
+}
+
 func FollowFeed(s *core.State, cmd core.Command, currentUser database.User) error {
 	if len(cmd.Args) < 1 {
 		return fmt.Errorf("the follow handler expects a single argument, the feed url")
 	}
 
-	feed, err := s.Db.GetFeedByUrl(context.Background(), cmd.Args[0])
+	feed, err := s.Db.GetFeedByUrl(s.Ctx, canonical.URL(cmd.Args[0]))
 	if err != nil {
 		return fmt.Errorf("feed with the requested url does not exist")
 	}
 
-	follow, err := s.Db.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
+	follow, err := s.Db.CreateFeedFollow(s.Ctx, database.CreateFeedFollowParams{
 		ID:        uuid.New(),
 		FeedID:    feed.ID,
 		UserID:    currentUser.ID,
@@ -78,13 +170,16 @@ func FollowFeed(s *core.State, cmd core.Command, currentUser database.User) erro
 }
 
 func FeedFollowsForUser(s *core.State, _ core.Command, currentUser database.User) error {
-	feeds, err := s.Db.GetFeedsForUser(context.Background(), currentUser.Name)
+	feeds, err := s.Db.GetFeedsForUser(s.Ctx, currentUser.Name)
 	if err != nil {
 		return fmt.Errorf("failed getting feeds for user: %s\n", err)
 	}
 
 	for _, feed := range feeds {
 		fmt.Printf("- '%s'\n", feed.Name)
+		printOptional("    Title", feed.Title)
+		printOptional("    Site", feed.SiteUrl)
+		printOptional("    Description", feed.Description)
 	}
 
 	return nil
@@ -95,12 +190,12 @@ func UnfollowFeed(s *core.State, cmd core.Command, currentUser database.User) er
 		return fmt.Errorf("the unfollow handler expects a single argument, the feed url")
 	}
 
-	feed, err := s.Db.GetFeedByUrl(context.Background(), cmd.Args[0])
+	feed, err := s.Db.GetFeedByUrl(s.Ctx, canonical.URL(cmd.Args[0]))
 	if err != nil {
 		return fmt.Errorf("feed with the requested url does not exist")
 	}
 
-	_, err = s.Db.UnfollowFeed(context.Background(), database.UnfollowFeedParams{
+	_, err = s.Db.UnfollowFeed(s.Ctx, database.UnfollowFeedParams{
 		UserID: currentUser.ID,
 		FeedID: feed.ID,
 	})
@@ -111,116 +206,107 @@ func UnfollowFeed(s *core.State, cmd core.Command, currentUser database.User) er
 	return nil
 }
 
-func FetchFeeds(s *core.State, _ core.Command) error {
-	feeds, err := s.Db.GetFeedsWithUserName(context.Background())
+func FetchFeeds(s *core.State, cmd core.Command) error {
+	if len(cmd.Args) > 0 && cmd.Args[0] == "--broken" {
+		return brokenFeeds(s)
+	}
+
+	feeds, err := s.Db.GetFeedsWithUserName(s.Ctx)
 	if err != nil {
 		return err
 	}
 
 	for _, feed := range feeds {
 		fmt.Printf("Name: %s\n", feed.Name)
-		fmt.Printf("URL: %s\n", feed.Url)
+		printOptional("Title", feed.Title)
+		fmt.Printf("URL: %s\n", feed.OriginalUrl)
+		printOptional("Site", feed.SiteUrl)
+		printOptional("Description", feed.Description)
+		printOptional("Language", feed.Language)
+		printOptional("Image", feed.ImageUrl)
+		printOptional("Generator", feed.Generator)
 		fmt.Printf("User: %s\n", feed.UserName)
+		if feed.DisabledAt.Valid {
+			fmt.Printf("Disabled: %s\n", feed.DisabledAt.Time.Format(time.DateTime))
+		}
 		fmt.Println()
 	}
 
 	return nil
 }
 
-func Browse(s *core.State, cmd core.Command, currentUser database.User) error {
-	var limit int
-	if len(cmd.Args) < 1 {
-		limit = 2
-	} else {
-		parsedInt, err := strconv.Atoi(cmd.Args[0])
-		if err != nil {
-			limit = 2
-		} else {
-			limit = parsedInt
-		}
-	}
-
-	posts, err := s.Db.GetPostsForUser(context.Background(), database.GetPostsForUserParams{
-		UserID: currentUser.ID,
-		Limit:  int32(limit),
-	})
+// brokenFeeds lists the feeds whose last fetches failed, including the ones
+// that got disabled.
+func brokenFeeds(s *core.State) error {
+	feeds, err := s.Db.GetBrokenFeeds(s.Ctx)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed getting broken feeds: %s", err)
+	}
+	if len(feeds) == 0 {
+		fmt.Println("No broken feeds")
+		return nil
 	}
 
-	for _, post := range posts {
-		fmt.Printf("Title: %s\n", post.Title)
+	for _, feed := range feeds {
+		fmt.Printf("Name: %s\n", feed.Name)
+		fmt.Printf("URL: %s\n", feed.OriginalUrl)
+		fmt.Printf("Failures in a row: %d\n", feed.ConsecutiveFailures)
+		if feed.LastStatus.Valid {
+			fmt.Printf("Last status: %d\n", feed.LastStatus.Int32)
+		}
+		printOptional("Last error", feed.LastError)
+		if feed.LastSuccessAt.Valid {
+			fmt.Printf("Last success: %s\n", feed.LastSuccessAt.Time.Format(time.DateTime))
+		} else {
+			fmt.Println("Last success: never")
+		}
+		if feed.DisabledAt.Valid {
+			fmt.Printf("Disabled: %s, revive with gator feed enable %s\n", feed.DisabledAt.Time.Format(time.DateTime), feed.OriginalUrl)
+		} else if feed.NextFetchAt.Valid {
+			fmt.Printf("Next attempt: %s\n", feed.NextFetchAt.Time.Format(time.DateTime))
+		}
+		fmt.Println()
 	}
 
 	return nil
 }
 
-func AggregateFeeds(s *core.State, cmd core.Command) error {
+// Feed dispatches the feed subcommands, currently only enable.
+func Feed(s *core.State, cmd core.Command) error {
 	if len(cmd.Args) < 1 {
-		return fmt.Errorf("the agg handler expects a single argument, the time interval how oftern to fetch feeds")
+		return fmt.Errorf("the feed handler expects a subcommand: feed enable <url>")
 	}
 
-	duration, err := time.ParseDuration(cmd.Args[0])
-	if err != nil {
-		return fmt.Errorf("failed parsing duration: %s", err)
-	}
-
-	fmt.Printf("Collecting feeds every %s\n", duration.String()+"")
-
-	ticker := time.NewTicker(duration)
-	defer ticker.Stop()
-
-	for ; ; <-ticker.C {
-		err := scrapeFeeds(s)
-		if err != nil {
-			fmt.Printf("failed scraping feed: %s\n", err)
-		}
+	switch cmd.Args[0] {
+	case "enable":
+		return enableFeed(s, cmd.Args[1:])
+	default:
+		return fmt.Errorf("unknown feed subcommand %s", cmd.Args[0])
 	}
 }
 
-func scrapeFeeds(s *core.State) error {
-	nextFeed, err := s.Db.GetNextFeedToFetch(context.Background())
-	if err != nil {
-		return fmt.Errorf("failed getting next feed to fetch: %s", err)
-	}
-
-	fmt.Printf("Fetching items for feed: %s\n", nextFeed.Name)
-
-	_, err = s.Db.MarkFeedFetched(context.Background(), nextFeed.ID)
-	if err != nil {
-		return err
+// enableFeed revives a disabled feed and clears its failures, so the next agg
+// cycle fetches it right away.
+func enableFeed(s *core.State, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("the feed enable handler expects a single argument, the feed url")
 	}
 
-	feed, err := rss.FetchFeed(context.Background(), nextFeed.Url)
+	updated, err := s.Db.EnableFeed(s.Ctx, canonical.URL(args[0]))
 	if err != nil {
-		return fmt.Errorf("failed fetching feed: %s", err)
+		return fmt.Errorf("failed enabling feed: %s", err)
 	}
-
-	for _, item := range feed.Channel.Item {
-		parsedTime, err := time.Parse(time.RFC1123Z, item.PubDate)
-		validTime := err == nil
-		if err != nil {
-			fmt.Printf("failed parsing time for post %s: %s\n", item.Title, err)
-		}
-		_, err = s.Db.CreatePost(context.Background(), database.CreatePostParams{
-			Title:       item.Title,
-			Url:         sql.NullString{String: item.Link, Valid: item.Link != ""},
-			Description: sql.NullString{String: item.Description, Valid: item.Description != ""},
-			PublishedAt: sql.NullTime{Time: parsedTime, Valid: validTime},
-			FeedID:      nextFeed.ID,
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
-		})
-		if err != nil {
-			var pqError *pq.Error
-			if errors.As(err, &pqError) && pqError.Code == "23505" {
-				// duplicate key for URL, we can either ignore it, updated it or just log it
-				// for now we'll ignore
-				continue
-			}
-			fmt.Printf("failed creating post with title %s: %s\n", item.Title, err)
-		}
+	if updated == 0 {
+		return fmt.Errorf("feed with the requested url does not exist")
 	}
 
+	fmt.Printf("Feed %s enabled\n", args[0])
 	return nil
 }
+
+// printOptional prints a labeled value, skipping it when it's not set.
+func printOptional(label string, value sql.NullString) {
+	if value.Valid && value.String != "" {
+		fmt.Printf("%s: %s\n", label, value.String)
+	}
+}
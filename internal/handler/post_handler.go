@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"gator/internal/core"
+	"gator/internal/database"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Browse prints the latest posts of the followed feeds:
+// browse [limit] [--author <name>] [--category <name>] [--content]
+func Browse(s *core.State, cmd core.Command, currentUser database.User) error {
+	params := database.GetPostsForUserParams{
+		UserID: currentUser.ID,
+		Limit:  2,
+	}
+	showContent := false
+
+	for i := 0; i < len(cmd.Args); i++ {
+		switch arg := cmd.Args[i]; arg {
+		case "--author", "--category":
+			if i+1 >= len(cmd.Args) {
+				return fmt.Errorf("%s expects a value", arg)
+			}
+			i++
+			if arg == "--author" {
+				params.Author = sql.NullString{String: cmd.Args[i], Valid: true}
+			} else {
+				params.Category = sql.NullString{String: cmd.Args[i], Valid: true}
+			}
+		case "--content":
+			showContent = true
+		default:
+			parsedInt, err := strconv.Atoi(arg)
+			if err == nil {
+				params.Limit = int32(parsedInt)
+			}
+		}
+	}
+
+	posts, err := s.Db.GetPostsForUser(s.Ctx, params)
+	if err != nil {
+		return err
+	}
+
+	postIDs := make([]uuid.UUID, 0, len(posts))
+	for _, post := range posts {
+		postIDs = append(postIDs, post.ID)
+	}
+	postCategories, err := s.Db.GetPostCategories(s.Ctx, postIDs)
+	if err != nil {
+		return fmt.Errorf("failed getting post categories: %s", err)
+	}
+	categories := make(map[uuid.UUID][]string)
+	for _, category := range postCategories {
+		categories[category.PostID] = append(categories[category.PostID], category.Name)
+	}
+	postEnclosures, err := s.Db.GetPostEnclosures(s.Ctx, postIDs)
+	if err != nil {
+		return fmt.Errorf("failed getting post enclosures: %s", err)
+	}
+	enclosures := make(map[uuid.UUID][]database.PostEnclosure)
+	for _, enclosure := range postEnclosures {
+		enclosures[enclosure.PostID] = append(enclosures[enclosure.PostID], enclosure)
+	}
+
+	for _, post := range posts {
+		fmt.Printf("Title: %s\n", post.Title)
+		printOptional("Author", post.Author)
+		if len(categories[post.ID]) > 0 {
+			fmt.Printf("Categories: %s\n", strings.Join(categories[post.ID], ", "))
+		}
+		if post.Episode.Valid {
+			fmt.Printf("Episode: %d\n", post.Episode.Int32)
+		}
+		printOptional("Image", post.ImageUrl)
+		for _, enclosure := range enclosures[post.ID] {
+			fmt.Printf("Enclosure: %s\n", describeEnclosure(enclosure))
+		}
+		fmt.Printf("ID: %s\n", post.ID)
+		if showContent {
+			if post.Content.Valid {
+				printOptional("Content", post.Content)
+			} else {
+				printOptional("Description", post.Description)
+			}
+		}
+	}
+
+	return nil
+}
+
+// describeEnclosure formats the URL of an enclosure followed by its type,
+// size and duration, as far as they are known.
+func describeEnclosure(enclosure database.PostEnclosure) string {
+	var details []string
+	if enclosure.MimeType.Valid {
+		details = append(details, enclosure.MimeType.String)
+	}
+	if enclosure.Length.Valid {
+		details = append(details, fmt.Sprintf("%.1f MB", float64(enclosure.Length.Int64)/(1<<20)))
+	}
+	if enclosure.DurationSeconds.Valid {
+		details = append(details, (time.Duration(enclosure.DurationSeconds.Int32) * time.Second).String())
+	}
+	if len(details) == 0 {
+		return enclosure.Url
+	}
+	return fmt.Sprintf("%s (%s)", enclosure.Url, strings.Join(details, ", "))
+}
+
+// Post dispatches the post subcommands, currently only history.
+func Post(s *core.State, cmd core.Command) error {
+	if len(cmd.Args) < 1 {
+		return fmt.Errorf("the post handler expects a subcommand: post history <id>")
+	}
+
+	switch cmd.Args[0] {
+	case "history":
+		return postHistory(s, cmd.Args[1:])
+	default:
+		return fmt.Errorf("unknown post subcommand %s", cmd.Args[0])
+	}
+}
+
+func postHistory(s *core.State, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("the post history handler expects a single argument, the post id")
+	}
+
+	postID, err := uuid.Parse(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid post id %s: %s", args[0], err)
+	}
+
+	post, err := s.Db.GetPost(s.Ctx, postID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("post with the requested id does not exist")
+	}
+	if err != nil {
+		return fmt.Errorf("failed getting post: %s", err)
+	}
+
+	revisions, err := s.Db.GetPostRevisions(s.Ctx, postID)
+	if err != nil {
+		return fmt.Errorf("failed getting post revisions: %s", err)
+	}
+
+	fmt.Printf("Current version, updated %s\n", post.UpdatedAt.Format(time.DateTime))
+	originalURL := post.OriginalUrl
+	if !originalURL.Valid {
+		originalURL = post.Url
+	}
+	printPostVersion(post.Title, originalURL, post.Description, post.PublishedAt)
+
+	if len(revisions) == 0 {
+		fmt.Println("No earlier versions")
+		return nil
+	}
+	for _, revision := range revisions {
+		fmt.Println()
+		fmt.Printf("Replaced %s\n", revision.CreatedAt.Format(time.DateTime))
+		printPostVersion(revision.Title, revision.Url, revision.Description, revision.PublishedAt)
+	}
+
+	return nil
+}
+
+func printPostVersion(title string, url, description sql.NullString, publishedAt sql.NullTime) {
+	fmt.Printf("  Title: %s\n", title)
+	printOptional("  URL", url)
+	if publishedAt.Valid {
+		fmt.Printf("  Published: %s\n", publishedAt.Time.Format(time.DateTime))
+	}
+	printOptional("  Description", description)
+}
@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"context"
 	"fmt"
 	"gator/internal/core"
 	"gator/internal/database"
@@ -16,7 +15,7 @@ func Login(s *core.State, cmd core.Command) error {
 		return fmt.Errorf("the login handler expects a single argument, the username")
 	}
 
-	user, err := s.Db.GetUser(context.Background(), cmd.Args[0])
+	user, err := s.Db.GetUser(s.Ctx, cmd.Args[0])
 	if err != nil {
 		return fmt.Errorf("the user with that name does not exist")
 	}
@@ -34,12 +33,12 @@ func Register(s *core.State, cmd core.Command) error {
 		return fmt.Errorf("the register handler expects a single argument, the username")
 	}
 
-	user, _ := s.Db.GetUser(context.Background(), cmd.Args[0])
+	user, _ := s.Db.GetUser(s.Ctx, cmd.Args[0])
 	if user.ID != uuid.Nil {
 		fmt.Println("user with that name already exists")
 		os.Exit(1)
 	}
-	createUser, err := s.Db.CreateUser(context.Background(), database.CreateUserParams{
+	createUser, err := s.Db.CreateUser(s.Ctx, database.CreateUserParams{
 		ID:        uuid.New(),
 		Name:      cmd.Args[0],
 		CreatedAt: time.Now(),
@@ -58,7 +57,7 @@ func Register(s *core.State, cmd core.Command) error {
 }
 
 func GetUsers(s *core.State, _ core.Command) error {
-	users, err := s.Db.GetUsers(context.Background())
+	users, err := s.Db.GetUsers(s.Ctx)
 	if err != nil {
 		return fmt.Errorf("failed getting users: %s", err)
 	}
@@ -74,7 +73,7 @@ func GetUsers(s *core.State, _ core.Command) error {
 }
 
 func Reset(s *core.State, _ core.Command) error {
-	err := s.Db.ClearUsers(context.Background())
+	err := s.Db.ClearUsers(s.Ctx)
 	if err != nil {
 		return fmt.Errorf("failed clearing users: %s", err)
 	} else {
@@ -0,0 +1,152 @@
+package rss
+
+import "strings"
+
+// atomFeed is the Atom 1.0 (RFC 4287) representation of a feed, it is only
+// used while parsing and gets normalized into a Feed.
+type atomFeed struct {
+	Lang      string                 `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Title     atomText               `xml:"title"`
+	Subtitle  atomText               `xml:"subtitle"`
+	Links     []atomLink             `xml:"link"`
+	Icon      string                 `xml:"icon"`
+	Logo      string                 `xml:"logo"`
+	Generator string                 `xml:"generator"`
+	Authors   []atomPerson           `xml:"author"`
+	Entries   limitedList[atomEntry] `xml:"entry"`
+	Base      string                 `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      atomText       `xml:"title"`
+	Links      []atomLink     `xml:"link"`
+	Summary    atomText       `xml:"summary"`
+	Content    atomText       `xml:"content"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Authors    []atomPerson   `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Base       string         `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+// atomCategory is an Atom category, the label is the human-readable
+// form of the term.
+type atomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr"`
+}
+
+type atomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+// atomText is an Atom text construct, type="xhtml" content is inline markup
+// so it has to be taken from the inner XML instead of the character data.
+type atomText struct {
+	Type     string `xml:"type,attr"`
+	Body     string `xml:",chardata"`
+	InnerXML string `xml:",innerxml"`
+}
+
+func (t atomText) String() string {
+	if t.Type == "xhtml" {
+		return strings.TrimSpace(t.InnerXML)
+	}
+	return strings.TrimSpace(t.Body)
+}
+
+func (f *atomFeed) toFeed() *Feed {
+	feed := &Feed{Channel: Channel{
+		Title:       f.Title.String(),
+		Link:        alternateLink(f.Links),
+		Description: f.Subtitle.String(),
+		Language:    f.Lang,
+		Image:       Image{URL: firstNonEmpty(f.Logo, f.Icon)},
+		Generator:   strings.TrimSpace(f.Generator),
+		Base:        f.Base,
+	}}
+
+	// entries inherit the feed authors when they don't list their own
+	feedAuthor := personNames(f.Authors)
+	for _, entry := range f.Entries.items {
+		description := entry.Content.String()
+		if description == "" {
+			description = entry.Summary.String()
+		}
+		published := entry.Published
+		if published == "" {
+			published = entry.Updated
+		}
+		author := personNames(entry.Authors)
+		if author == "" {
+			author = feedAuthor
+		}
+		var enclosures []RSSEnclosure
+		for _, link := range entry.Links {
+			if link.Rel == "enclosure" {
+				enclosures = append(enclosures, RSSEnclosure{URL: link.Href, Type: link.Type, Length: link.Length})
+			}
+		}
+		var categories []string
+		for _, category := range entry.Categories {
+			categories = append(categories, firstNonEmpty(category.Label, category.Term))
+		}
+		feed.Channel.Item = append(feed.Channel.Item, Item{
+			Title:       entry.Title.String(),
+			Link:        alternateLink(entry.Links),
+			Description: description,
+			Content:     entry.Content.String(),
+			PubDate:     published,
+			Updated:     entry.Updated,
+			Guid:        strings.TrimSpace(entry.ID),
+			Author:      author,
+			Categories:  categories,
+			Enclosures:  enclosures,
+			Base:        entry.Base,
+		})
+	}
+
+	return feed
+}
+
+// personNames joins the names of Atom authors.
+func personNames(people []atomPerson) string {
+	var names []string
+	for _, person := range people {
+		if name := strings.TrimSpace(person.Name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// alternateLink returns the href of the rel="alternate" link, a link without
+// rel is alternate by definition. Falls back to the first link.
+func alternateLink(links []atomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return link.Href
+		}
+	}
+	if len(links) > 0 {
+		return links[0].Href
+	}
+	return ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,64 @@
+package rss
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"regexp"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+)
+
+// xmlEncoding matches the encoding of an XML declaration.
+var xmlEncoding = regexp.MustCompile(`^\s*<\?xml[^>]*\sencoding\s*=\s*["']([A-Za-z0-9._:-]+)["']`)
+
+// toUTF8 transcodes an XML document to UTF-8. The encoding is taken from a
+// byte order mark, else from the charset of the Content-Type header, else
+// from the XML declaration at the start of head, and defaults to UTF-8.
+// Invalid byte sequences are replaced by U+FFFD and characters XML doesn't
+// allow are dropped, so a few broken bytes don't fail the whole feed.
+func toUTF8(body io.Reader, head []byte, contentType string) io.Reader {
+	var enc encoding.Encoding = unicode.UTF8
+	if label := declaredCharset(head, contentType); label != "" {
+		if declared, _ := charset.Lookup(label); declared != nil {
+			enc = declared
+		}
+	}
+
+	return transform.NewReader(body, transform.Chain(
+		unicode.BOMOverride(enc.NewDecoder()),
+		runes.ReplaceIllFormed(),
+		runes.Remove(runes.Predicate(invalidXMLRune)),
+	))
+}
+
+// declaredCharset returns the charset named by the Content-Type header or,
+// when it doesn't name one, by the XML declaration.
+func declaredCharset(head []byte, contentType string) string {
+	if _, params, err := mime.ParseMediaType(contentType); err == nil && params["charset"] != "" {
+		return params["charset"]
+	}
+	if match := xmlEncoding.FindSubmatch(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))); match != nil {
+		return string(match[1])
+	}
+	return ""
+}
+
+// invalidXMLRune reports the characters outside of the XML 1.0 Char
+// production, mostly control characters that slip into feeds.
+func invalidXMLRune(r rune) bool {
+	return !(r == '\t' || r == '\n' || r == '\r' ||
+		r >= 0x20 && r <= 0xd7ff ||
+		r >= 0xe000 && r <= 0xfffd ||
+		r >= 0x10000 && r <= 0x10ffff)
+}
+
+// utf8CharsetReader is the CharsetReader of a decoder reading a document
+// already transcoded by toUTF8, the encoding it declares no longer applies.
+func utf8CharsetReader(_ string, input io.Reader) (io.Reader, error) {
+	return input, nil
+}
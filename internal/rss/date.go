@@ -0,0 +1,180 @@
+package rss
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// dateLayouts are tried in order by ParseDate once the value has been
+// normalized, i.e. weekday dropped, month names in English and timezone
+// abbreviations replaced by numeric offsets.
+var dateLayouts = []string{
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 -07:00",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05",
+	"2 Jan 2006 15:04",
+	"2 Jan 06 15:04:05 -0700",
+	"2 Jan 06 15:04 -0700",
+	"2 Jan 06 15:04:05",
+	"2 Jan 2006",
+	"2-Jan-06 15:04:05 -0700",
+	"2-Jan-2006 15:04:05 -0700",
+	"2-Jan-06 15:04:05",
+	"Jan 2 15:04:05 -0700 2006",
+	"Jan 2 15:04:05 2006",
+	"Jan 2, 2006 15:04:05 -0700",
+	"Jan 2, 2006 15:04:05",
+	"Jan 2, 2006 15:04",
+	"Jan 2, 2006",
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02T15:04:05.999999999-0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05 -07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"2006/01/02",
+}
+
+// timezones maps the abbreviations seen in the wild to their UTC offset,
+// time.Parse accepts unknown abbreviations but silently treats them as UTC.
+var timezones = map[string]string{
+	"z": "+0000", "ut": "+0000", "utc": "+0000", "gmt": "+0000", "wet": "+0000",
+	"bst": "+0100", "cet": "+0100", "met": "+0100", "west": "+0100",
+	"cest": "+0200", "mest": "+0200", "eet": "+0200", "sast": "+0200",
+	"eest": "+0300", "msk": "+0300",
+	"edt": "-0400", "ast": "-0400",
+	"est": "-0500", "cdt": "-0500",
+	"cst": "-0600", "mdt": "-0600",
+	"mst": "-0700", "pdt": "-0700",
+	"pst": "-0800", "akdt": "-0800",
+	"akst": "-0900", "hst": "-1000",
+	"nzdt": "+1300", "nzst": "+1200",
+	"aedt": "+1100", "aest": "+1000", "acst": "+0930", "awst": "+0800",
+	"jst": "+0900", "kst": "+0900", "hkt": "+0800", "sgt": "+0800",
+	// IST is Irish Summer Time as well, but far more often India in feeds
+	"ist": "+0530",
+}
+
+// months maps English and common European month names and abbreviations to
+// the English abbreviation understood by time.Parse.
+var months = map[string]string{}
+
+func init() {
+	names := map[string][]string{
+		"Jan": {"january", "jan", "januar", "janvier", "janv", "enero", "ene", "gennaio", "gen", "januari", "janeiro"},
+		"Feb": {"february", "feb", "februar", "février", "févr", "fevrier", "fevr", "febrero", "febbraio", "februari", "fevereiro", "fev"},
+		"Mar": {"march", "mar", "märz", "mär", "maerz", "mars", "marzo", "maart", "mrt", "março", "marco"},
+		"Apr": {"april", "apr", "avril", "avr", "abril", "abr", "aprile"},
+		"May": {"may", "mai", "mayo", "maggio", "mag", "mei", "maio"},
+		"Jun": {"june", "jun", "juni", "juin", "junio", "giugno", "giu", "junho"},
+		"Jul": {"july", "jul", "juli", "juillet", "juil", "julio", "luglio", "lug", "julho"},
+		"Aug": {"august", "aug", "août", "aout", "agosto", "ago", "augustus"},
+		"Sep": {"september", "sep", "sept", "septembre", "septiembre", "settembre", "set", "setembro"},
+		"Oct": {"october", "oct", "oktober", "okt", "octobre", "octubre", "ottobre", "ott", "outubro", "out"},
+		"Nov": {"november", "nov", "novembre", "noviembre", "novembro"},
+		"Dec": {"december", "dec", "dezember", "dez", "décembre", "déc", "decembre", "diciembre", "dic", "dicembre", "dezembro"},
+	}
+	for month, aliases := range names {
+		for _, alias := range aliases {
+			months[alias] = month
+		}
+	}
+}
+
+var weekdays = map[string]bool{
+	"mon": true, "tue": true, "wed": true, "thu": true, "fri": true, "sat": true, "sun": true,
+	"monday": true, "tuesday": true, "wednesday": true, "thursday": true, "friday": true, "saturday": true, "sunday": true,
+}
+
+// ParseDate parses the publish dates found in real-world feeds: RFC 822/1123
+// with numeric offsets or zone names, RFC 850, two-digit years, missing
+// seconds, ISO 8601/RFC 3339 and month names in several European languages.
+// Values without a timezone are assumed to be UTC.
+func ParseDate(value string) (time.Time, error) {
+	normalized := normalizeDate(value)
+	if normalized == "" {
+		return time.Time{}, fmt.Errorf("empty date")
+	}
+
+	for _, layout := range dateLayouts {
+		if parsed, err := time.Parse(layout, normalized); err == nil {
+			return parsed, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized date format: %q", value)
+}
+
+func normalizeDate(value string) string {
+	fields := strings.Fields(value)
+	// a trailing comment like "+0000 (UTC)" only repeats the offset
+	for i, field := range fields {
+		if i > 0 && strings.HasPrefix(field, "(") {
+			fields = fields[:i]
+			break
+		}
+	}
+	if len(fields) == 0 {
+		return ""
+	}
+
+	// the weekday carries no information and is often wrong or localized
+	first := strings.ToLower(strings.TrimRight(fields[0], ",."))
+	if strings.HasSuffix(fields[0], ",") && isAlpha(first) || weekdays[first] {
+		fields = fields[1:]
+	}
+
+	for i, field := range fields {
+		trimmed := strings.TrimRight(field, ".,")
+		key := strings.ToLower(trimmed)
+		if !isAlpha(key) {
+			continue
+		}
+		if month, ok := months[key]; ok {
+			// keep a trailing comma as in "January 2, 2006", drop abbreviation dots
+			fields[i] = month + strings.ReplaceAll(field[len(trimmed):], ".", "")
+			continue
+		}
+		if offset, ok := timezones[key]; ok && i == len(fields)-1 {
+			fields[i] = offset
+		}
+	}
+
+	return strings.Join(fields, " ")
+}
+
+func isAlpha(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range value {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// PublishedAt returns the publish date of the item in UTC, trying pubDate,
+// then dc:date and finally the Atom updated date.
+func (i Item) PublishedAt() (time.Time, bool) {
+	for _, value := range []string{i.PubDate, i.DcDate, i.Updated} {
+		if value == "" {
+			continue
+		}
+		if parsed, err := ParseDate(value); err == nil {
+			return parsed.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,118 @@
+package rss
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		// RFC 1123 with numeric offsets and zone names
+		{"Mon, 02 Jan 2006 15:04:05 -0700", "2006-01-02T22:04:05Z"},
+		{"Mon, 02 Jan 2006 15:04:05 +0000", "2006-01-02T15:04:05Z"},
+		{"Tue, 10 Jun 2003 04:00:00 GMT", "2003-06-10T04:00:00Z"},
+		{"Sat, 07 Sep 2002 00:00:01 GMT", "2002-09-07T00:00:01Z"},
+		{"Wed, 02 Oct 2002 13:00:00 EST", "2002-10-02T18:00:00Z"},
+		{"Thu, 21 Mar 2024 09:30:00 PDT", "2024-03-21T16:30:00Z"},
+		{"Fri, 15 Mar 2024 18:00:00 IST", "2024-03-15T12:30:00Z"},
+		{"Mon, 02 Jan 2006 15:04:05 UT", "2006-01-02T15:04:05Z"},
+		{"Mon, 02 Jan 2006 15:04:05 Z", "2006-01-02T15:04:05Z"},
+		{"Mon, 02 Jan 2006 15:04:05 +0000 (UTC)", "2006-01-02T15:04:05Z"},
+		{"Mon, 02 Jan 2006 15:04:05 -0500 (EST)", "2006-01-02T20:04:05Z"},
+		{"Mon, 2 Jan 2006 15:04:05 +01:00", "2006-01-02T14:04:05Z"},
+		// RFC 822 with two-digit years and without seconds
+		{"Mon, 02 Jan 06 15:04:05 +0200", "2006-01-02T13:04:05Z"},
+		{"02 Jan 06 15:04 +0000", "2006-01-02T15:04:00Z"},
+		{"Mon, 02 Jan 2006 15:04 +0000", "2006-01-02T15:04:00Z"},
+		// RFC 850
+		{"Wednesday, 02-Jan-06 15:04:05 PST", "2006-01-02T23:04:05Z"},
+		{"Sunday, 06-Nov-94 08:49:37 GMT", "1994-11-06T08:49:37Z"},
+		// sloppy RFC 822: missing comma, full names, no timezone
+		{"Mon 02 Jan 2006 15:04:05 +0000", "2006-01-02T15:04:05Z"},
+		{"Monday, 02 January 2006 15:04:05 +0000", "2006-01-02T15:04:05Z"},
+		{"02 Jan 2006 15:04:05", "2006-01-02T15:04:05Z"},
+		{"2 January 2006", "2006-01-02T00:00:00Z"},
+		{"January 2, 2006", "2006-01-02T00:00:00Z"},
+		{"Jan 2, 2006 3:04:05", "2006-01-02T03:04:05Z"},
+		{"Mon Jan 2 15:04:05 -0700 2006", "2006-01-02T22:04:05Z"},
+		// ISO 8601 / RFC 3339
+		{"2006-01-02T15:04:05Z", "2006-01-02T15:04:05Z"},
+		{"2006-01-02T15:04:05+02:00", "2006-01-02T13:04:05Z"},
+		{"2006-01-02T15:04:05.123456Z", "2006-01-02T15:04:05.123456Z"},
+		{"2006-01-02T15:04:05-0700", "2006-01-02T22:04:05Z"},
+		{"2006-01-02T15:04Z", "2006-01-02T15:04:00Z"},
+		{"2006-01-02T15:04:05", "2006-01-02T15:04:05Z"},
+		{"2006-01-02 15:04:05", "2006-01-02T15:04:05Z"},
+		{"2006-01-02 15:04:05 +0000", "2006-01-02T15:04:05Z"},
+		{"2006-01-02", "2006-01-02T00:00:00Z"},
+		{"2006/01/02 15:04:05", "2006-01-02T15:04:05Z"},
+		// non-English month and weekday names
+		{"Mo, 02 Jan 2006 15:04:05 +0100", "2006-01-02T14:04:05Z"},
+		{"Di, 14 Mär 2023 10:00:00 +0100", "2023-03-14T09:00:00Z"},
+		{"14 März 2023 10:00:00 +0100", "2023-03-14T09:00:00Z"},
+		{"mer., 4 déc. 2019 08:00:00 +0100", "2019-12-04T07:00:00Z"},
+		{"4 févr. 2019 08:00:00 +0100", "2019-02-04T07:00:00Z"},
+		{"lun, 03 ene 2022 12:00:00 +0000", "2022-01-03T12:00:00Z"},
+		{"15 agosto 2021 20:15:00 +0200", "2021-08-15T18:15:00Z"},
+		{"1 mei 2020 09:00:00 +0200", "2020-05-01T07:00:00Z"},
+		// surrounding whitespace
+		{"  Mon, 02 Jan 2006 15:04:05 GMT\n", "2006-01-02T15:04:05Z"},
+	}
+
+	for _, test := range tests {
+		got, err := ParseDate(test.value)
+		if err != nil {
+			t.Errorf("ParseDate(%q) failed: %s", test.value, err)
+			continue
+		}
+		want, err := time.Parse(time.RFC3339Nano, test.want)
+		if err != nil {
+			t.Fatalf("invalid expectation %q: %s", test.want, err)
+		}
+		if !got.Equal(want) {
+			t.Errorf("ParseDate(%q) = %s, want %s", test.value, got.UTC().Format(time.RFC3339Nano), test.want)
+		}
+	}
+}
+
+func TestParseDateInvalid(t *testing.T) {
+	for _, value := range []string{"", "   ", "yesterday", "not a date", "32 Jan 2006", "2006-13-01"} {
+		if got, err := ParseDate(value); err == nil {
+			t.Errorf("ParseDate(%q) = %s, want an error", value, got)
+		}
+	}
+}
+
+func TestItemPublishedAt(t *testing.T) {
+	tests := []struct {
+		name string
+		item Item
+		want string
+		ok   bool
+	}{
+		{"pubDate", Item{PubDate: "Mon, 02 Jan 2006 15:04:05 GMT", DcDate: "2010-01-01"}, "2006-01-02T15:04:05Z", true},
+		{"dc:date fallback", Item{PubDate: "garbage", DcDate: "2010-01-01T10:00:00+01:00"}, "2010-01-01T09:00:00Z", true},
+		{"updated fallback", Item{Updated: "2011-05-05T05:05:05Z"}, "2011-05-05T05:05:05Z", true},
+		{"undated", Item{PubDate: "soon"}, "", false},
+	}
+
+	for _, test := range tests {
+		got, ok := test.item.PublishedAt()
+		if ok != test.ok {
+			t.Errorf("%s: PublishedAt() ok = %t, want %t", test.name, ok, test.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if got.Location() != time.UTC {
+			t.Errorf("%s: PublishedAt() = %s, want it in UTC", test.name, got)
+		}
+		if formatted := got.Format(time.RFC3339); formatted != test.want {
+			t.Errorf("%s: PublishedAt() = %s, want %s", test.name, formatted, test.want)
+		}
+	}
+}
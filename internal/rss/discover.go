@@ -0,0 +1,159 @@
+package rss
+
+import (
+	"bytes"
+	"context"
+	"mime"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Candidate is a feed found by Discover.
+type Candidate struct {
+	URL   string
+	Title string
+	Type  string
+	// Feed is the parsed feed when it was downloaded while discovering it,
+	// so it doesn't have to be fetched again. It's nil for the feeds only
+	// advertised by a page.
+	Feed *Feed
+}
+
+// feedTypes are the link types advertised by sites for their feeds.
+var feedTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/rdf+xml":   true,
+	"application/feed+json": true,
+	"application/json":      true,
+}
+
+// commonFeedPaths are probed when an HTML page doesn't advertise any feed.
+var commonFeedPaths = []string{"/feed", "/rss.xml", "/index.xml", "/atom.xml", "/feed.xml", "/rss", "/feed.json"}
+
+// Discover returns the feeds available at pageURL. When pageURL already is a
+// feed it is the only candidate. For HTML pages the <link rel="alternate">
+// tags are used, falling back to probing commonFeedPaths on the same host.
+func (f *Fetcher) Discover(ctx context.Context, pageURL string) ([]Candidate, error) {
+	doc, err := f.fetchDocument(ctx, pageURL, requestOptions{}, readBody)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := doc.Header.Get("Content-Type")
+	feed, parseErr := parseFeed(bytes.NewReader(doc.Body), contentType, f.maxItems)
+	if parseErr == nil {
+		finishFeed(feed, doc)
+		return []Candidate{{URL: doc.URL.String(), Title: feed.Channel.Title, Feed: feed}}, nil
+	}
+	if !isHTML(doc.Body, contentType) {
+		return nil, parseErr
+	}
+
+	candidates := feedLinks(doc.Body, doc.URL)
+	if len(candidates) > 0 {
+		return candidates, nil
+	}
+
+	// several of the paths often redirect to the same feed
+	seen := make(map[string]bool)
+	for _, path := range commonFeedPaths {
+		probeURL := doc.URL.ResolveReference(&url.URL{Path: path})
+		probe, err := f.fetchDocument(ctx, probeURL.String(), requestOptions{}, readBody)
+		if err != nil || seen[probe.URL.String()] {
+			continue
+		}
+		seen[probe.URL.String()] = true
+		feed, err := parseFeed(bytes.NewReader(probe.Body), probe.Header.Get("Content-Type"), f.maxItems)
+		if err != nil {
+			continue
+		}
+		finishFeed(feed, probe)
+		candidates = append(candidates, Candidate{URL: probe.URL.String(), Title: feed.Channel.Title, Feed: feed})
+	}
+
+	return candidates, nil
+}
+
+// feedLinks extracts the feeds advertised in the <head> of an HTML page,
+// resolving relative hrefs against the page URL or its <base href>.
+func feedLinks(page []byte, pageURL *url.URL) []Candidate {
+	var candidates []Candidate
+	seen := make(map[string]bool)
+	base := pageURL
+
+	tokenizer := html.NewTokenizer(bytes.NewReader(page))
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			return candidates
+		}
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			continue
+		}
+
+		token := tokenizer.Token()
+		switch token.Data {
+		case "base":
+			if href, err := url.Parse(attribute(token, "href")); err == nil {
+				base = pageURL.ResolveReference(href)
+			}
+		case "link":
+			if !hasToken(attribute(token, "rel"), "alternate") {
+				continue
+			}
+			linkType := strings.ToLower(strings.TrimSpace(attribute(token, "type")))
+			if !feedTypes[linkType] {
+				continue
+			}
+			href, err := url.Parse(strings.TrimSpace(attribute(token, "href")))
+			if err != nil || href.String() == "" {
+				continue
+			}
+			resolved := base.ResolveReference(href).String()
+			if seen[resolved] {
+				continue
+			}
+			seen[resolved] = true
+			candidates = append(candidates, Candidate{
+				URL:   resolved,
+				Title: attribute(token, "title"),
+				Type:  linkType,
+			})
+		case "body":
+			// feeds are only advertised in the head
+			return candidates
+		}
+	}
+}
+
+func attribute(token html.Token, name string) string {
+	for _, attr := range token.Attr {
+		if attr.Key == name {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// hasToken reports whether the space separated list, like a rel attribute,
+// contains value.
+func hasToken(list, value string) bool {
+	for _, field := range strings.Fields(strings.ToLower(list)) {
+		if field == value {
+			return true
+		}
+	}
+	return false
+}
+
+func isHTML(data []byte, contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil && (mediaType == "text/html" || mediaType == "application/xhtml+xml") {
+		return true
+	}
+	prefix := strings.ToLower(string(bytes.TrimSpace(data[:min(len(data), 512)])))
+	return strings.HasPrefix(prefix, "<!doctype html") || strings.Contains(prefix, "<html")
+}
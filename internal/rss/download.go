@@ -0,0 +1,53 @@
+package rss
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Download fetches the file at fileURL, an enclosure for example, without
+// the size limit of feeds. A positive offset resumes a partial download with
+// a Range request. open is called with the offset the response starts at,
+// zero when the server sent the whole file anyway, and the total size of the
+// file or zero when it's unknown, and returns where to write the response.
+func (f *Fetcher) Download(ctx context.Context, fileURL string, offset int64, open func(start, total int64) (io.Writer, error)) error {
+	_, err := f.fetchDocument(ctx, fileURL, requestOptions{offset: offset, unlimited: true}, func(doc *document, body io.Reader) error {
+		start, total := int64(0), parseLength(doc.Header.Get("Content-Length"))
+		if doc.StatusCode == http.StatusPartialContent {
+			start, total = contentRange(doc.Header.Get("Content-Range"))
+		}
+
+		w, err := open(start, total)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w, body)
+		return err
+	})
+
+	// the range starts past the end when the file was complete already
+	var statusErr *StatusError
+	if offset > 0 && errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		_, err := open(offset, offset)
+		return err
+	}
+	return err
+}
+
+// contentRange parses a Content-Range header like "bytes 100-199/200" into
+// the first byte and the total size, which is zero when it's unknown.
+func contentRange(value string) (int64, int64) {
+	value = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(value), "bytes"))
+	span, size, _ := strings.Cut(value, "/")
+	first, _, _ := strings.Cut(span, "-")
+
+	start, err := strconv.ParseInt(strings.TrimSpace(first), 10, 64)
+	if err != nil {
+		start = 0
+	}
+	return start, parseLength(size)
+}
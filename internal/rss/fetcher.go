@@ -0,0 +1,352 @@
+package rss
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultUserAgent is sent when Options doesn't set one.
+const DefaultUserAgent = "gator/1.0 (RSS feed aggregator)"
+
+// Options configure a Fetcher, zero values fall back to the defaults.
+type Options struct {
+	// UserAgent is sent with every request
+	UserAgent string
+	// ConnectTimeout bounds dialing and the TLS handshake
+	ConnectTimeout time.Duration
+	// ReadTimeout bounds waiting for the response headers and every read of
+	// the body, so a stalled server fails even if the whole fetch has time left
+	ReadTimeout time.Duration
+	// MaxBodySize is the largest response body read, in bytes
+	MaxBodySize int64
+	// MaxItems is the number of items parsed per feed, zero for all of them
+	MaxItems int
+	// Proxy overrides the HTTP_PROXY/HTTPS_PROXY/NO_PROXY environment
+	Proxy string
+	// CABundle is a PEM file with certificates trusted on top of the system ones
+	CABundle string
+	// HostConcurrency is the number of requests running at once per host and
+	// HostDelay the time between the starts of two requests to a host. A
+	// fetch waiting longer than HostMaxWait for its turn is deferred.
+	HostConcurrency int
+	HostDelay       time.Duration
+	HostMaxWait     time.Duration
+	// Robots makes the fetcher obey robots.txt, including its Crawl-delay
+	Robots bool
+}
+
+// Fetcher fetches feeds and pages over HTTP. It's safe for concurrent use
+// and meant to be shared, so connections are reused between fetches.
+type Fetcher struct {
+	client      *http.Client
+	userAgent   string
+	readTimeout time.Duration
+	maxBodySize int64
+	maxItems    int
+
+	hostConcurrency int
+	hostDelay       time.Duration
+	hostMaxWait     time.Duration
+	robots          bool
+
+	hostsMu sync.Mutex
+	hosts   map[string]*host
+}
+
+// BodyTooLargeError is returned when a response body exceeds MaxBodySize.
+type BodyTooLargeError struct {
+	Limit int64
+}
+
+func (e *BodyTooLargeError) Error() string {
+	return fmt.Sprintf("response body exceeds %d bytes", e.Limit)
+}
+
+func NewFetcher(options Options) (*Fetcher, error) {
+	if options.UserAgent == "" {
+		options.UserAgent = DefaultUserAgent
+	}
+	if options.ConnectTimeout <= 0 {
+		options.ConnectTimeout = 10 * time.Second
+	}
+	if options.ReadTimeout <= 0 {
+		options.ReadTimeout = 30 * time.Second
+	}
+	if options.MaxBodySize <= 0 {
+		options.MaxBodySize = 10 << 20
+	}
+	if options.HostConcurrency <= 0 {
+		options.HostConcurrency = 2
+	}
+	if options.HostMaxWait <= 0 {
+		options.HostMaxWait = 10 * time.Second
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: options.ConnectTimeout, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = options.ConnectTimeout
+	transport.ResponseHeaderTimeout = options.ReadTimeout
+
+	if options.Proxy != "" {
+		proxyURL, err := url.Parse(options.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %s: %s", options.Proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if options.CABundle != "" {
+		pool, err := certPool(options.CABundle)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	return &Fetcher{
+		client:      &http.Client{Transport: transport},
+		userAgent:   options.UserAgent,
+		readTimeout: options.ReadTimeout,
+		maxBodySize: options.MaxBodySize,
+		maxItems:    options.MaxItems,
+
+		hostConcurrency: options.HostConcurrency,
+		hostDelay:       options.HostDelay,
+		hostMaxWait:     options.HostMaxWait,
+		robots:          options.Robots,
+		hosts:           make(map[string]*host),
+	}, nil
+}
+
+// certPool returns the system certificates together with the ones in the
+// PEM file at path.
+func certPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading CA bundle: %s", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in CA bundle %s", path)
+	}
+	return pool, nil
+}
+
+// document is a fetched response body together with the final URL, after
+// redirects, and the response headers needed to interpret it.
+type document struct {
+	URL *url.URL
+	// MovedTo is where the document permanently moved to: the target of the
+	// last 301 or 308 before the first temporary redirect, if any, since
+	// whatever follows a temporary redirect may change again
+	MovedTo    string
+	StatusCode int
+	Header     http.Header
+	// Body is only set when the document was read with readBody
+	Body []byte
+}
+
+// readBody keeps the whole body in the document, it's passed to fetchDocument
+// when the document isn't parsed on the fly.
+func readBody(doc *document, body io.Reader) error {
+	var err error
+	doc.Body, err = io.ReadAll(body)
+	return err
+}
+
+// fetchDocument fetches a document once the politeness limits of its host
+// allow it and hands the body of a successful response to read while it's
+// still downloading. Redirects are followed without waiting for the hosts
+// they lead to.
+func (f *Fetcher) fetchDocument(ctx context.Context, documentURL string, options requestOptions, read func(*document, io.Reader) error) (*document, error) {
+	target, err := url.Parse(documentURL)
+	if err != nil {
+		return nil, err
+	}
+
+	h := f.host(target.Host)
+	release, err := h.acquire(ctx, f.hostDelay, f.hostMaxWait)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	if f.robots {
+		if err := f.checkRobots(ctx, h, target); err != nil {
+			return nil, err
+		}
+	}
+
+	return f.get(ctx, documentURL, options, read)
+}
+
+// requestOptions adapt a single request to what it fetches.
+type requestOptions struct {
+	validators Validators
+	// offset asks for the document from that byte on with a Range header
+	offset int64
+	// unlimited lifts MaxBodySize, for files rather than feeds
+	unlimited bool
+}
+
+func (f *Fetcher) get(ctx context.Context, documentURL string, options requestOptions, read func(*document, io.Reader) error) (*document, error) {
+	requestCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(requestCtx, http.MethodGet, documentURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("User-Agent", f.userAgent)
+	request.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/feed+json, application/xml;q=0.9, text/xml;q=0.9, text/html;q=0.8, */*;q=0.5")
+	if options.validators.ETag != "" {
+		request.Header.Set("If-None-Match", options.validators.ETag)
+	}
+	if options.validators.LastModified != "" {
+		request.Header.Set("If-Modified-Since", options.validators.LastModified)
+	}
+	if options.offset > 0 {
+		request.Header.Set("Range", fmt.Sprintf("bytes=%d-", options.offset))
+	}
+
+	// every fetch gets its own client to keep track of its redirects, they
+	// all share the transport and with it the connections
+	var movedTo *url.URL
+	temporary := false
+	client := *f.client
+	client.CheckRedirect = func(request *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		switch request.Response.StatusCode {
+		case http.StatusMovedPermanently, http.StatusPermanentRedirect:
+			if !temporary {
+				movedTo = request.URL
+			}
+		default:
+			temporary = true
+		}
+		return nil
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			fmt.Printf("failed closing response body: %s\n", err)
+		}
+	}(response.Body)
+
+	doc := &document{
+		URL:        response.Request.URL,
+		StatusCode: response.StatusCode,
+		Header:     response.Header,
+	}
+	if movedTo != nil && movedTo.String() != documentURL {
+		doc.MovedTo = movedTo.String()
+	}
+
+	if response.StatusCode == http.StatusNotModified {
+		return doc, ErrNotModified
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return nil, &StatusError{
+			StatusCode: response.StatusCode,
+			Status:     response.Status,
+			RetryAfter: retryAfter(response.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+	var body io.Reader = response.Body
+	if !options.unlimited {
+		if response.ContentLength > f.maxBodySize {
+			return nil, &BodyTooLargeError{Limit: f.maxBodySize}
+		}
+		body = &limitedReader{reader: body, limit: f.maxBodySize}
+	}
+
+	// the request is canceled when a single read stalls for longer than
+	// the read timeout
+	stalled := time.AfterFunc(f.readTimeout, cancel)
+	defer stalled.Stop()
+	err = read(doc, &idleReader{reader: body, timer: stalled, timeout: f.readTimeout})
+	if err != nil && requestCtx.Err() != nil && ctx.Err() == nil {
+		return nil, fmt.Errorf("reading response body timed out after %s", f.readTimeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// limitedReader fails with a BodyTooLargeError once more than limit bytes
+// are read, unlike io.LimitReader which silently stops.
+type limitedReader struct {
+	reader io.Reader
+	limit  int64
+	read   int64
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if r.read > r.limit {
+		return 0, &BodyTooLargeError{Limit: r.limit}
+	}
+	if left := r.limit - r.read + 1; int64(len(p)) > left {
+		p = p[:left]
+	}
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	if r.read > r.limit {
+		return n, &BodyTooLargeError{Limit: r.limit}
+	}
+	return n, err
+}
+
+// retryAfter parses a Retry-After header, given either in seconds or as an
+// HTTP date. It returns the zero time when there's none.
+func retryAfter(value string, now time.Time) time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return now.Add(time.Duration(seconds) * time.Second)
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return date
+	}
+	return time.Time{}
+}
+
+// idleReader pushes timer back by timeout after every successful read.
+type idleReader struct {
+	reader  io.Reader
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.timer.Reset(r.timeout)
+	}
+	return n, err
+}
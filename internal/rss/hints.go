@@ -0,0 +1,66 @@
+package rss
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CacheTTL returns the channel <ttl>, the number of minutes the feed may be
+// cached before refreshing it, zero when missing or invalid.
+func (c Channel) CacheTTL() time.Duration {
+	minutes, err := strconv.Atoi(strings.TrimSpace(c.TTL))
+	if err != nil || minutes <= 0 {
+		return 0
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// SkippedHours returns the valid <skipHours> of the channel, hours in GMT.
+func (c Channel) SkippedHours() []int {
+	var hours []int
+	for _, value := range c.SkipHours {
+		hour, err := strconv.Atoi(strings.TrimSpace(value))
+		if err == nil && hour >= 0 && hour <= 23 {
+			hours = append(hours, hour)
+		}
+	}
+	return hours
+}
+
+// SkippedDays returns the valid <skipDays> of the channel.
+func (c Channel) SkippedDays() []time.Weekday {
+	var days []time.Weekday
+	for _, value := range c.SkipDays {
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			if strings.EqualFold(strings.TrimSpace(value), day.String()) {
+				days = append(days, day)
+			}
+		}
+	}
+	return days
+}
+
+// cacheExpiry returns until when the response is fresh according to its
+// Cache-Control max-age or, without it, its Expires header. The zero time
+// means there is no usable expiry.
+func cacheExpiry(header http.Header, now time.Time) time.Time {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(strings.ToLower(directive)), "=")
+		switch name {
+		case "no-cache", "no-store":
+			return time.Time{}
+		case "max-age":
+			seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+			if err == nil && seconds > 0 {
+				return now.Add(time.Duration(seconds) * time.Second)
+			}
+		}
+	}
+
+	if expires, err := http.ParseTime(header.Get("Expires")); err == nil {
+		return expires
+	}
+	return time.Time{}
+}
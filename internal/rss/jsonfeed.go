@@ -0,0 +1,139 @@
+package rss
+
+import (
+	"bytes"
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// jsonFeed is the JSON Feed 1.0/1.1 representation of a feed, see
+// https://www.jsonfeed.org/version/1.1/. Like atomFeed it is normalized into a Feed.
+type jsonFeed struct {
+	Version     string           `json:"version"`
+	Title       string           `json:"title"`
+	HomePageURL string           `json:"home_page_url"`
+	FeedURL     string           `json:"feed_url"`
+	Description string           `json:"description"`
+	Icon        string           `json:"icon"`
+	Favicon     string           `json:"favicon"`
+	Language    string           `json:"language"`
+	Author      *jsonFeedAuthor  `json:"author"`
+	Authors     []jsonFeedAuthor `json:"authors"`
+	Items       []jsonFeedItem   `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string               `json:"id"`
+	URL           string               `json:"url"`
+	ExternalURL   string               `json:"external_url"`
+	Title         string               `json:"title"`
+	ContentHTML   string               `json:"content_html"`
+	ContentText   string               `json:"content_text"`
+	Summary       string               `json:"summary"`
+	DatePublished string               `json:"date_published"`
+	DateModified  string               `json:"date_modified"`
+	Author        *jsonFeedAuthor      `json:"author"`
+	Authors       []jsonFeedAuthor     `json:"authors"`
+	Tags          []string             `json:"tags"`
+	Image         string               `json:"image"`
+	Attachments   []jsonFeedAttachment `json:"attachments"`
+}
+
+type jsonFeedAttachment struct {
+	URL               string  `json:"url"`
+	MimeType          string  `json:"mime_type"`
+	SizeInBytes       int64   `json:"size_in_bytes"`
+	DurationInSeconds float64 `json:"duration_in_seconds"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+func (f *jsonFeed) toFeed() *Feed {
+	feed := &Feed{Channel: Channel{
+		Title:       f.Title,
+		Link:        f.HomePageURL,
+		Description: f.Description,
+		Language:    f.Language,
+		Image:       Image{URL: firstNonEmpty(f.Icon, f.Favicon)},
+	}}
+
+	// items inherit the feed authors when they don't list their own
+	feedAuthor := authorNames(f.Author, f.Authors)
+	for _, item := range f.Items {
+		link := item.URL
+		if link == "" {
+			link = item.ExternalURL
+		}
+		description := item.ContentHTML
+		if description == "" {
+			description = item.ContentText
+		}
+		if description == "" {
+			description = item.Summary
+		}
+		published := item.DatePublished
+		if published == "" {
+			published = item.DateModified
+		}
+		author := authorNames(item.Author, item.Authors)
+		if author == "" {
+			author = feedAuthor
+		}
+		converted := Item{
+			Title:       item.Title,
+			Link:        link,
+			Description: description,
+			PubDate:     published,
+			Updated:     item.DateModified,
+			Guid:        item.ID,
+			Author:      author,
+			Content:     firstNonEmpty(item.ContentHTML, item.ContentText),
+			Categories:  item.Tags,
+		}
+		// attachments carry their own size and duration like media:content
+		for _, attachment := range item.Attachments {
+			converted.MediaContent = append(converted.MediaContent, MediaContent{
+				URL:      attachment.URL,
+				Type:     attachment.MimeType,
+				FileSize: strconv.FormatInt(attachment.SizeInBytes, 10),
+				Duration: strconv.FormatFloat(attachment.DurationInSeconds, 'f', -1, 64),
+			})
+		}
+		if item.Image != "" {
+			converted.MediaThumbnail = []MediaThumbnail{{URL: item.Image}}
+		}
+		feed.Channel.Item = append(feed.Channel.Item, converted)
+	}
+
+	return feed
+}
+
+// authorNames joins the names of the 1.1 authors list, falling back to the
+// deprecated 1.0 single author object.
+func authorNames(author *jsonFeedAuthor, authors []jsonFeedAuthor) string {
+	var names []string
+	for _, a := range authors {
+		if a.Name != "" {
+			names = append(names, a.Name)
+		}
+	}
+	if len(names) == 0 && author != nil && author.Name != "" {
+		names = append(names, author.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// isJSONFeed reports whether the document is a JSON Feed, either by the
+// declared content type or by sniffing the first non-whitespace byte.
+func isJSONFeed(data []byte, contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil && (mediaType == "application/feed+json" || mediaType == "application/json") {
+		return true
+	}
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
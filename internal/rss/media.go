@@ -0,0 +1,120 @@
+package rss
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Enclosure is a media file attached to an item, the audio of a podcast
+// episode for example. Length and Duration are zero when the feed doesn't
+// tell them.
+type Enclosure struct {
+	URL      string
+	Type     string
+	Length   int64
+	Duration time.Duration
+}
+
+// RSSEnclosure is the <enclosure> element of an RSS item.
+type RSSEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+// MediaContent is a media:content element of Media RSS.
+type MediaContent struct {
+	URL      string `xml:"url,attr"`
+	Type     string `xml:"type,attr"`
+	FileSize string `xml:"fileSize,attr"`
+	Duration string `xml:"duration,attr"`
+}
+
+// MediaThumbnail is a media:thumbnail element of Media RSS.
+type MediaThumbnail struct {
+	URL string `xml:"url,attr"`
+}
+
+// Media returns the enclosures of the item followed by its Media RSS
+// content, without repeating a URL. The itunes:duration applies to the
+// enclosures that don't state their own.
+func (i Item) Media() []Enclosure {
+	var media []Enclosure
+	seen := make(map[string]bool)
+	add := func(enclosure Enclosure) {
+		enclosure.URL = strings.TrimSpace(enclosure.URL)
+		if enclosure.URL == "" || seen[enclosure.URL] {
+			return
+		}
+		seen[enclosure.URL] = true
+		media = append(media, enclosure)
+	}
+
+	duration := parseDuration(i.ItunesDuration)
+	for _, enclosure := range i.Enclosures {
+		add(Enclosure{
+			URL:      enclosure.URL,
+			Type:     strings.TrimSpace(enclosure.Type),
+			Length:   parseLength(enclosure.Length),
+			Duration: duration,
+		})
+	}
+	for _, content := range append(i.MediaContent, i.MediaGroup...) {
+		enclosure := Enclosure{
+			URL:      content.URL,
+			Type:     strings.TrimSpace(content.Type),
+			Length:   parseLength(content.FileSize),
+			Duration: parseDuration(content.Duration),
+		}
+		if enclosure.Duration == 0 {
+			enclosure.Duration = duration
+		}
+		add(enclosure)
+	}
+	return media
+}
+
+// ImageURL returns the image of the item, its media:thumbnail or else its
+// itunes:image.
+func (i Item) ImageURL() string {
+	for _, thumbnail := range i.MediaThumbnail {
+		if url := strings.TrimSpace(thumbnail.URL); url != "" {
+			return url
+		}
+	}
+	return firstNonEmpty(i.ItunesImage.Href, i.ItunesImage.URL)
+}
+
+// Episode returns the itunes:episode number of the item.
+func (i Item) Episode() (int, bool) {
+	episode, err := strconv.Atoi(strings.TrimSpace(i.ItunesEpisode))
+	return episode, err == nil && episode > 0
+}
+
+func parseLength(value string) int64 {
+	length, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || length < 0 {
+		return 0
+	}
+	return length
+}
+
+// parseDuration reads an itunes:duration, given in seconds or as MM:SS or
+// HH:MM:SS, the Media RSS duration attribute being seconds as well.
+func parseDuration(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	var seconds float64
+	for _, part := range strings.Split(value, ":") {
+		number, err := strconv.ParseFloat(part, 64)
+		if err != nil || number < 0 {
+			return 0
+		}
+		seconds = seconds*60 + number
+	}
+	return time.Duration(seconds * float64(time.Second)).Round(time.Second)
+}
@@ -0,0 +1,90 @@
+package rss
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DeferredError is returned when a fetch wasn't started to keep the load on
+// a host down, the feed should simply be tried again later.
+type DeferredError struct {
+	Host   string
+	Reason string
+}
+
+func (e *DeferredError) Error() string {
+	return fmt.Sprintf("deferred to spare %s: %s", e.Host, e.Reason)
+}
+
+// host keeps track of the requests to a single host, so they stay within the
+// concurrency and delay limits of the Fetcher, and caches its robots.txt.
+type host struct {
+	name  string
+	slots chan struct{}
+
+	mu sync.Mutex
+	// next is the earliest time the next request may start
+	next time.Time
+	// crawlDelay is the Crawl-delay of robots.txt, if longer than the delay
+	crawlDelay time.Duration
+
+	robotsMu      sync.Mutex
+	robots        *robotsPolicy
+	robotsExpires time.Time
+}
+
+// host returns the limits of the named host, creating them on first use.
+func (f *Fetcher) host(name string) *host {
+	f.hostsMu.Lock()
+	defer f.hostsMu.Unlock()
+
+	h, ok := f.hosts[name]
+	if !ok {
+		h = &host{name: name, slots: make(chan struct{}, f.hostConcurrency)}
+		f.hosts[name] = h
+	}
+	return h
+}
+
+// acquire waits until a request to the host may start, at most maxWait,
+// and returns the function releasing the slot once the request is done.
+// A DeferredError is returned when the host stays busy for longer.
+func (h *host) acquire(ctx context.Context, delay, maxWait time.Duration) (func(), error) {
+	deadline := time.Now().Add(maxWait)
+	timer := time.NewTimer(maxWait)
+	defer timer.Stop()
+
+	select {
+	case h.slots <- struct{}{}:
+	case <-timer.C:
+		return nil, &DeferredError{Host: h.name, Reason: fmt.Sprintf("%d requests already running", cap(h.slots))}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	release := func() { <-h.slots }
+
+	h.mu.Lock()
+	start := time.Now()
+	if h.next.After(start) {
+		start = h.next
+	}
+	if start.After(deadline) {
+		h.mu.Unlock()
+		release()
+		return nil, &DeferredError{Host: h.name, Reason: fmt.Sprintf("next request allowed at %s", start.Format(time.TimeOnly))}
+	}
+	h.next = start.Add(max(delay, h.crawlDelay))
+	h.mu.Unlock()
+
+	wait := time.NewTimer(time.Until(start))
+	defer wait.Stop()
+	select {
+	case <-wait.C:
+		return release, nil
+	case <-ctx.Done():
+		release()
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,145 @@
+package rss
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestHost(concurrency int) *host {
+	return &host{name: "example.com", slots: make(chan struct{}, concurrency)}
+}
+
+func TestAcquireConcurrency(t *testing.T) {
+	h := newTestHost(2)
+	ctx := context.Background()
+
+	first, err := h.acquire(ctx, 0, 20*time.Millisecond)
+	if err != nil {
+		t.Fatalf("first acquire failed: %s", err)
+	}
+	second, err := h.acquire(ctx, 0, 20*time.Millisecond)
+	if err != nil {
+		t.Fatalf("second acquire failed: %s", err)
+	}
+
+	var deferred *DeferredError
+	if _, err := h.acquire(ctx, 0, 20*time.Millisecond); !errors.As(err, &deferred) {
+		t.Fatalf("third acquire = %v, want a DeferredError while both slots are taken", err)
+	}
+
+	first()
+	third, err := h.acquire(ctx, 0, 20*time.Millisecond)
+	if err != nil {
+		t.Fatalf("acquire after a release failed: %s", err)
+	}
+	second()
+	third()
+}
+
+func TestAcquireWaitsForSlot(t *testing.T) {
+	h := newTestHost(1)
+	release, err := h.acquire(context.Background(), 0, time.Second)
+	if err != nil {
+		t.Fatalf("acquire failed: %s", err)
+	}
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		release()
+	}()
+
+	release, err = h.acquire(context.Background(), 0, time.Second)
+	if err != nil {
+		t.Fatalf("acquire waiting for the released slot failed: %s", err)
+	}
+	release()
+}
+
+func TestAcquireDelay(t *testing.T) {
+	tests := []struct {
+		name       string
+		delay      time.Duration
+		crawlDelay time.Duration
+		want       time.Duration
+	}{
+		{"delay", 60 * time.Millisecond, 0, 60 * time.Millisecond},
+		{"longer crawl delay", 10 * time.Millisecond, 60 * time.Millisecond, 60 * time.Millisecond},
+		{"shorter crawl delay", 60 * time.Millisecond, 10 * time.Millisecond, 60 * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		h := newTestHost(2)
+		h.crawlDelay = test.crawlDelay
+
+		start := time.Now()
+		release, err := h.acquire(context.Background(), test.delay, time.Second)
+		if err != nil {
+			t.Fatalf("%s: first acquire failed: %s", test.name, err)
+		}
+		release()
+		release, err = h.acquire(context.Background(), test.delay, time.Second)
+		if err != nil {
+			t.Fatalf("%s: second acquire failed: %s", test.name, err)
+		}
+		release()
+
+		if elapsed := time.Since(start); elapsed < test.want {
+			t.Errorf("%s: second request started after %s, want at least %s", test.name, elapsed, test.want)
+		}
+	}
+}
+
+func TestAcquireDeferredByDelay(t *testing.T) {
+	h := newTestHost(2)
+	release, err := h.acquire(context.Background(), time.Hour, time.Second)
+	if err != nil {
+		t.Fatalf("first acquire failed: %s", err)
+	}
+	release()
+
+	start := time.Now()
+	var deferred *DeferredError
+	if _, err := h.acquire(context.Background(), time.Hour, time.Second); !errors.As(err, &deferred) {
+		t.Fatalf("second acquire = %v, want a DeferredError when the delay exceeds the wait", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("deferring took %s, want it right away", elapsed)
+	}
+	// the deferred request doesn't keep its slot
+	if len(h.slots) != 0 {
+		t.Errorf("%d slots taken after deferring, want none", len(h.slots))
+	}
+}
+
+func TestAcquireCanceled(t *testing.T) {
+	h := newTestHost(1)
+	release, err := h.acquire(context.Background(), time.Hour, time.Hour)
+	if err != nil {
+		t.Fatalf("first acquire failed: %s", err)
+	}
+	defer release()
+
+	// waiting for a slot
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := h.acquire(ctx, 0, time.Hour); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("acquire waiting for a slot = %v, want the context error", err)
+	}
+
+	// waiting for the delay
+	h2 := newTestHost(1)
+	first, err := h2.acquire(context.Background(), time.Hour, time.Hour)
+	if err != nil {
+		t.Fatalf("first acquire failed: %s", err)
+	}
+	first()
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel2()
+	if _, err := h2.acquire(ctx2, time.Hour, 2*time.Hour); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("acquire waiting for the delay = %v, want the context error", err)
+	}
+	if len(h2.slots) != 0 {
+		t.Errorf("%d slots taken after canceling, want none", len(h2.slots))
+	}
+}
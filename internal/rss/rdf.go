@@ -0,0 +1,47 @@
+package rss
+
+// rdfFeed is the RSS 1.0 (RDF) representation of a feed. Unlike RSS 2.0 the
+// items are siblings of the channel element instead of being nested in it.
+type rdfFeed struct {
+	Base    string `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	Channel struct {
+		Title       rssTitle `xml:"title"`
+		Link        string   `xml:"link"`
+		Description string   `xml:"description"`
+		Language    string   `xml:"http://purl.org/dc/elements/1.1/ language"`
+	} `xml:"channel"`
+	Image struct {
+		URL string `xml:"url"`
+	} `xml:"image"`
+	Items limitedList[rdfItem] `xml:"item"`
+}
+
+type rdfItem struct {
+	Item
+	Title rssTitle `xml:"title"`
+	About string   `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+}
+
+func (f *rdfFeed) toFeed() *Feed {
+	feed := &Feed{Channel: Channel{
+		Title:       string(f.Channel.Title),
+		Link:        f.Channel.Link,
+		Description: f.Channel.Description,
+		Language:    f.Channel.Language,
+		Image:       Image{URL: f.Image.URL},
+		Base:        f.Base,
+	}}
+
+	for _, rdfItem := range f.Items.items {
+		item := rdfItem.Item
+		item.Title = string(rdfItem.Title)
+		item.Link = firstLink(item.Links)
+		if item.Guid == "" {
+			item.Guid = rdfItem.About
+		}
+		feed.Channel.Item = append(feed.Channel.Item, item)
+	}
+	unescapeItems(feed.Channel.Item)
+
+	return feed
+}
@@ -0,0 +1,190 @@
+package rss
+
+import (
+	"bytes"
+	"io"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// resolveURLs makes the relative URLs of a feed absolute, the links of the
+// channel and its items as well as their media and the links and images
+// inside their HTML descriptions and content.
+//
+// The channel is resolved against its xml:base, which in turn is relative
+// to the URL the feed was fetched from. Items resolve against their own
+// xml:base, and without one in scope against the channel link, since relative
+// item links usually point at the website rather than the feed host.
+func (feed *Feed) resolveURLs(documentURL *url.URL) {
+	channelBase := joinBase(documentURL, feed.Channel.Base)
+	feed.Channel.Link = resolveURL(channelBase, feed.Channel.Link)
+	feed.Channel.Image.URL = resolveURL(channelBase, feed.Channel.Image.URL)
+	feed.Channel.Image.Href = resolveURL(channelBase, feed.Channel.Image.Href)
+
+	itemsBase := channelBase
+	if feed.Channel.Base == "" {
+		if link, err := url.Parse(feed.Channel.Link); err == nil && link.IsAbs() && link.Host != "" {
+			itemsBase = link
+		}
+	}
+
+	for i := range feed.Channel.Item {
+		item := &feed.Channel.Item[i]
+		base := itemsBase
+		if item.Base != "" {
+			base = joinBase(channelBase, item.Base)
+		}
+
+		item.Link = resolveURL(base, item.Link)
+		item.ItunesImage.Href = resolveURL(base, item.ItunesImage.Href)
+		item.ItunesImage.URL = resolveURL(base, item.ItunesImage.URL)
+		for j := range item.Enclosures {
+			item.Enclosures[j].URL = resolveURL(base, item.Enclosures[j].URL)
+		}
+		for j := range item.MediaContent {
+			item.MediaContent[j].URL = resolveURL(base, item.MediaContent[j].URL)
+		}
+		for j := range item.MediaGroup {
+			item.MediaGroup[j].URL = resolveURL(base, item.MediaGroup[j].URL)
+		}
+		for j := range item.MediaThumbnail {
+			item.MediaThumbnail[j].URL = resolveURL(base, item.MediaThumbnail[j].URL)
+		}
+		item.Description = resolveHTML(base, item.Description)
+		item.Content = resolveHTML(base, item.Content)
+	}
+}
+
+// joinBase applies an xml:base to the base URL in scope, keeping the base
+// when the xml:base is missing or invalid.
+func joinBase(base *url.URL, xmlBase string) *url.URL {
+	xmlBase = strings.TrimSpace(xmlBase)
+	if xmlBase == "" {
+		return base
+	}
+	ref, err := url.Parse(xmlBase)
+	if err != nil {
+		return base
+	}
+	if base == nil {
+		return ref
+	}
+	return base.ResolveReference(ref)
+}
+
+// resolveURL makes ref absolute against base. Empty and invalid references
+// are returned as they are, and so are bare fragments which point within
+// the item itself.
+func resolveURL(base *url.URL, ref string) string {
+	trimmed := strings.TrimSpace(ref)
+	if base == nil || trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return ref
+	}
+	parsed, err := url.Parse(trimmed)
+	if err != nil || (parsed.IsAbs() && parsed.Host != "") {
+		return ref
+	}
+	return base.ResolveReference(parsed).String()
+}
+
+// urlAttributes are the HTML attributes holding a URL, srcset holds a list
+// of them.
+var urlAttributes = map[string]bool{
+	"href":   true,
+	"src":    true,
+	"poster": true,
+	"cite":   true,
+	"srcset": true,
+}
+
+// resolveHTML makes the URLs in the link and media attributes of an HTML
+// fragment absolute. Only tags that change are rewritten, everything else is
+// copied byte for byte, and text that isn't markup is returned untouched.
+func resolveHTML(base *url.URL, fragment string) string {
+	if base == nil || !strings.Contains(fragment, "<") {
+		return fragment
+	}
+
+	var out bytes.Buffer
+	changed := false
+	tokenizer := html.NewTokenizer(strings.NewReader(fragment))
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			if tokenizer.Err() != io.EOF {
+				return fragment
+			}
+			break
+		}
+		raw := tokenizer.Raw()
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			out.Write(raw)
+			continue
+		}
+
+		// Raw shares the buffer of the tokenizer, which Token lowercases the
+		// tag and attribute names in
+		raw = bytes.Clone(raw)
+		token := tokenizer.Token()
+		rewritten := false
+		for i, attr := range token.Attr {
+			if attr.Namespace != "" || !urlAttributes[attr.Key] {
+				continue
+			}
+			var value string
+			if attr.Key == "srcset" {
+				value = resolveSrcset(base, attr.Val)
+			} else {
+				value = resolveURL(base, attr.Val)
+			}
+			if value != attr.Val {
+				token.Attr[i].Val = value
+				rewritten = true
+			}
+		}
+		if rewritten {
+			out.WriteString(token.String())
+			changed = true
+		} else {
+			out.Write(raw)
+		}
+	}
+
+	if !changed {
+		return fragment
+	}
+	return out.String()
+}
+
+// resolveSrcset resolves the URLs of a srcset, candidates like "a.png 2x"
+// separated by commas.
+func resolveSrcset(base *url.URL, srcset string) string {
+	changed := false
+	candidates := strings.Split(srcset, ",")
+	for i, candidate := range candidates {
+		fields := strings.Fields(candidate)
+		if len(fields) == 0 {
+			continue
+		}
+		if resolved := resolveURL(base, fields[0]); resolved != fields[0] {
+			fields[0] = resolved
+			changed = true
+		}
+		candidates[i] = strings.Join(fields, " ")
+	}
+	if !changed {
+		return srcset
+	}
+	return strings.Join(candidates, ", ")
+}
+
+// joinXMLBase nests the xml:base of an element in the one of its parent.
+func joinXMLBase(parent, child string) string {
+	base := joinBase(nil, parent)
+	if base == nil {
+		return child
+	}
+	return joinBase(base, child).String()
+}
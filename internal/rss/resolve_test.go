@@ -0,0 +1,199 @@
+package rss
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func mustParseURL(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("invalid URL %q: %s", raw, err)
+	}
+	return parsed
+}
+
+func TestResolveURL(t *testing.T) {
+	base := mustParseURL(t, "https://example.com/blog/post.html")
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"image.png", "https://example.com/blog/image.png"},
+		{"../about", "https://example.com/about"},
+		{"/feed.xml", "https://example.com/feed.xml"},
+		{"//cdn.example.net/a.mp3", "https://cdn.example.net/a.mp3"},
+		{"?page=2", "https://example.com/blog/post.html?page=2"},
+		{" image.png ", "https://example.com/blog/image.png"},
+		{"https://other.example.org/x", "https://other.example.org/x"},
+		{"#comments", "#comments"},
+		{"", ""},
+		{"mailto:someone@example.com", "mailto:someone@example.com"},
+		{"%zz", "%zz"},
+	}
+
+	for _, test := range tests {
+		if got := resolveURL(base, test.ref); got != test.want {
+			t.Errorf("resolveURL(%q) = %q, want %q", test.ref, got, test.want)
+		}
+	}
+
+	if got := resolveURL(nil, "image.png"); got != "image.png" {
+		t.Errorf("resolveURL without a base = %q, want it unchanged", got)
+	}
+}
+
+func TestResolveHTML(t *testing.T) {
+	base := mustParseURL(t, "https://example.com/blog/")
+	tests := []struct {
+		name     string
+		fragment string
+		want     string
+	}{
+		{"plain text", "1 < 2 & no markup", "1 < 2 & no markup"},
+		{"text without tags", "just text", "just text"},
+		{"relative link", `<a href="post/1">read</a>`, `<a href="https://example.com/blog/post/1">read</a>`},
+		{"root relative image", `<img src="/img/a.png" alt="A">`, `<img src="https://example.com/img/a.png" alt="A">`},
+		{"media", `<video poster="p.jpg"><source src="v.mp4"></video>`, `<video poster="https://example.com/blog/p.jpg"><source src="https://example.com/blog/v.mp4"></video>`},
+		{"quote", `<blockquote cite="/source">q</blockquote>`, `<blockquote cite="https://example.com/source">q</blockquote>`},
+		{"srcset", `<img srcset="a.png 1x, /b.png 2x">`, `<img srcset="https://example.com/blog/a.png 1x, https://example.com/b.png 2x">`},
+		{"absolute links untouched", `<P CLASS="X">Hi <A HREF="https://abs.example.org/">there</A></P>`, `<P CLASS="X">Hi <A HREF="https://abs.example.org/">there</A></P>`},
+		{"fragments untouched", `<a href="#note-1">1</a>`, `<a href="#note-1">1</a>`},
+		{"untouched tags next to rewritten ones", `<P CLASS="X"><a href="x">x</a></P>`, `<P CLASS="X"><a href="https://example.com/blog/x">x</a></P>`},
+		{"entities kept", `<p>caf&eacute; &amp; <b>bar</b></p>`, `<p>caf&eacute; &amp; <b>bar</b></p>`},
+		{"comments kept", `<!-- <a href="x"> --><p>t</p>`, `<!-- <a href="x"> --><p>t</p>`},
+		{"other attributes kept", `<a data-href="x" title="y">z</a>`, `<a data-href="x" title="y">z</a>`},
+	}
+
+	for _, test := range tests {
+		if got := resolveHTML(base, test.fragment); got != test.want {
+			t.Errorf("%s: resolveHTML(%q) = %q, want %q", test.name, test.fragment, got, test.want)
+		}
+	}
+}
+
+func TestResolveSrcset(t *testing.T) {
+	base := mustParseURL(t, "https://example.com/blog/")
+	tests := []struct {
+		srcset string
+		want   string
+	}{
+		{"a.png", "https://example.com/blog/a.png"},
+		{"a.png 480w, b.png 800w", "https://example.com/blog/a.png 480w, https://example.com/blog/b.png 800w"},
+		{"https://cdn.example.net/a.png 1x,  b.png   2x", "https://cdn.example.net/a.png 1x, https://example.com/blog/b.png 2x"},
+		{"https://cdn.example.net/a.png 1x", "https://cdn.example.net/a.png 1x"},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		if got := resolveSrcset(base, test.srcset); got != test.want {
+			t.Errorf("resolveSrcset(%q) = %q, want %q", test.srcset, got, test.want)
+		}
+	}
+}
+
+func TestResolveURLs(t *testing.T) {
+	tests := []struct {
+		name        string
+		documentURL string
+		feed        string
+		channelLink string
+		itemLink    string
+		enclosure   string
+		description string
+	}{
+		{
+			name:        "relative to the feed URL",
+			documentURL: "https://feeds.example.com/blog/rss.xml",
+			feed: `<rss version="2.0"><channel><title>Blog</title><link>/</link>
+<item><link>posts/1</link><enclosure url="audio/1.mp3" type="audio/mpeg" length="1"/><description>&lt;img src="a.png"&gt;</description></item>
+</channel></rss>`,
+			channelLink: "https://feeds.example.com/",
+			itemLink:    "https://feeds.example.com/posts/1",
+			enclosure:   "https://feeds.example.com/audio/1.mp3",
+			description: `<img src="https://feeds.example.com/a.png">`,
+		},
+		{
+			name:        "items fall back to the channel link",
+			documentURL: "https://feeds.example.com/rss.xml",
+			feed: `<rss version="2.0"><channel><title>Blog</title><link>https://www.example.com/blog/</link>
+<item><link>posts/1</link><enclosure url="/audio/1.mp3" type="audio/mpeg" length="1"/><description>&lt;a href="#top"&gt;top&lt;/a&gt;</description></item>
+</channel></rss>`,
+			channelLink: "https://www.example.com/blog/",
+			itemLink:    "https://www.example.com/blog/posts/1",
+			enclosure:   "https://www.example.com/audio/1.mp3",
+			description: `<a href="#top">top</a>`,
+		},
+		{
+			name:        "channel xml:base",
+			documentURL: "https://feeds.example.com/rss.xml",
+			feed: `<rss version="2.0"><channel xml:base="https://static.example.com/blog/"><title>Blog</title><link>https://www.example.com/</link>
+<item><link>posts/1</link><enclosure url="1.mp3" type="audio/mpeg" length="1"/><description>text</description></item>
+</channel></rss>`,
+			channelLink: "https://www.example.com/",
+			itemLink:    "https://static.example.com/blog/posts/1",
+			enclosure:   "https://static.example.com/blog/1.mp3",
+			description: "text",
+		},
+		{
+			name:        "nested xml:base",
+			documentURL: "https://feeds.example.com/rss.xml",
+			feed: `<rss version="2.0" xml:base="https://example.com/site/"><channel xml:base="blog/"><title>Blog</title><link>https://www.example.com/</link>
+<item xml:base="2024/"><link>post</link><enclosure url="/audio/1.mp3" type="audio/mpeg" length="1"/><description>&lt;img srcset="a.png 1x, b.png 2x"&gt;</description></item>
+</channel></rss>`,
+			channelLink: "https://www.example.com/",
+			itemLink:    "https://example.com/site/blog/2024/post",
+			enclosure:   "https://example.com/audio/1.mp3",
+			description: `<img srcset="https://example.com/site/blog/2024/a.png 1x, https://example.com/site/blog/2024/b.png 2x">`,
+		},
+		{
+			name:        "relative xml:base against the feed URL",
+			documentURL: "https://feeds.example.com/blog/rss.xml",
+			feed: `<rss version="2.0"><channel xml:base="/media/"><title>Blog</title>
+<item><link>post</link><enclosure url="1.mp3" type="audio/mpeg" length="1"/><description>text</description></item>
+</channel></rss>`,
+			itemLink:    "https://feeds.example.com/media/post",
+			enclosure:   "https://feeds.example.com/media/1.mp3",
+			description: "text",
+		},
+		{
+			name:        "Atom entry xml:base",
+			documentURL: "https://example.com/atom.xml",
+			feed: `<feed xmlns="http://www.w3.org/2005/Atom" xml:base="https://example.com/blog/"><title>Blog</title>
+<entry xml:base="2024/"><id>1</id><title>Post</title><link href="post"/><link rel="enclosure" href="../1.mp3" type="audio/mpeg" length="1"/><content type="html">&lt;a href="../"&gt;up&lt;/a&gt;</content></entry>
+</feed>`,
+			itemLink:    "https://example.com/blog/2024/post",
+			enclosure:   "https://example.com/blog/1.mp3",
+			description: `<a href="https://example.com/blog/">up</a>`,
+		},
+	}
+
+	for _, test := range tests {
+		feed, err := parseFeed(strings.NewReader(test.feed), "application/xml", 0)
+		if err != nil {
+			t.Errorf("%s: parseFeed failed: %s", test.name, err)
+			continue
+		}
+		feed.resolveURLs(mustParseURL(t, test.documentURL))
+
+		if feed.Channel.Link != test.channelLink {
+			t.Errorf("%s: channel link = %q, want %q", test.name, feed.Channel.Link, test.channelLink)
+		}
+		if len(feed.Channel.Item) != 1 {
+			t.Errorf("%s: parseFeed returned %d items, want 1", test.name, len(feed.Channel.Item))
+			continue
+		}
+		item := feed.Channel.Item[0]
+		if item.Link != test.itemLink {
+			t.Errorf("%s: item link = %q, want %q", test.name, item.Link, test.itemLink)
+		}
+		if len(item.Enclosures) != 1 || item.Enclosures[0].URL != test.enclosure {
+			t.Errorf("%s: enclosures = %v, want %q", test.name, item.Enclosures, test.enclosure)
+		}
+		if item.Description != test.description {
+			t.Errorf("%s: description = %q, want %q", test.name, item.Description, test.description)
+		}
+	}
+}
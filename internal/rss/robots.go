@@ -0,0 +1,181 @@
+package rss
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DisallowedError is returned when robots.txt of the host doesn't allow
+// gator to fetch the URL.
+type DisallowedError struct {
+	URL string
+}
+
+func (e *DisallowedError) Error() string {
+	return fmt.Sprintf("%s is disallowed by robots.txt", e.URL)
+}
+
+// robotsPolicy holds the rules of a robots.txt that apply to gator.
+type robotsPolicy struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// allowed tells whether the path is allowed, the longest matching rule wins
+// and allow wins a tie. Everything is allowed without a matching rule.
+func (p *robotsPolicy) allowed(path string) bool {
+	allowed, longest := true, -1
+	for _, rule := range p.rules {
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		if len(rule.pattern) > longest || (len(rule.pattern) == longest && rule.allow) {
+			allowed, longest = rule.allow, len(rule.pattern)
+		}
+	}
+	return allowed
+}
+
+// robotsMatch matches a robots.txt path pattern, where * matches any
+// sequence of characters and a trailing $ anchors the end of the path.
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	for i, part := range parts[1:] {
+		if anchored && i == len(parts)-2 {
+			return strings.HasSuffix(rest, part)
+		}
+		index := strings.Index(rest, part)
+		if index < 0 {
+			return false
+		}
+		rest = rest[index+len(part):]
+	}
+	return !anchored || rest == ""
+}
+
+// parseRobots reads the rules of the groups naming agent, or of the *
+// group when no group names it. As in RFC 9309 a group names the agent when
+// its User-agent is the lowercase product token, ignoring case.
+func parseRobots(data []byte, agent string) *robotsPolicy {
+	var specific, wildcard robotsPolicy
+	hasSpecific := false
+
+	var groupAgents []string
+	inRules := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+
+		if key == "user-agent" {
+			if inRules {
+				groupAgents, inRules = nil, false
+			}
+			if value != "" {
+				groupAgents = append(groupAgents, strings.ToLower(value))
+			}
+			continue
+		}
+		if key != "allow" && key != "disallow" && key != "crawl-delay" {
+			continue
+		}
+		inRules = true
+
+		for _, groupAgent := range groupAgents {
+			var policy *robotsPolicy
+			switch {
+			case groupAgent == "*":
+				policy = &wildcard
+			case groupAgent == agent:
+				policy, hasSpecific = &specific, true
+			default:
+				continue
+			}
+
+			switch key {
+			case "crawl-delay":
+				if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+					policy.crawlDelay = time.Duration(seconds * float64(time.Second))
+				}
+			case "disallow":
+				// an empty Disallow allows everything
+				if value != "" {
+					policy.rules = append(policy.rules, robotsRule{allow: false, pattern: value})
+				}
+			case "allow":
+				policy.rules = append(policy.rules, robotsRule{allow: true, pattern: value})
+			}
+		}
+	}
+
+	if hasSpecific {
+		return &specific
+	}
+	return &wildcard
+}
+
+// robotsAgent is the product token of the User-Agent, the name robots.txt
+// groups are matched against.
+func robotsAgent(userAgent string) string {
+	token, _, _ := strings.Cut(userAgent, "/")
+	return strings.ToLower(strings.TrimSpace(token))
+}
+
+// checkRobots returns a DisallowedError when robots.txt of the host forbids
+// fetching target. The policy is cached per host for a day, a missing
+// robots.txt allows everything and one that can't be read is retried after
+// an hour.
+func (f *Fetcher) checkRobots(ctx context.Context, h *host, target *url.URL) error {
+	h.robotsMu.Lock()
+	defer h.robotsMu.Unlock()
+
+	if h.robots == nil || time.Now().After(h.robotsExpires) {
+		robotsURL := &url.URL{Scheme: target.Scheme, Host: target.Host, Path: "/robots.txt"}
+		doc, err := f.get(ctx, robotsURL.String(), requestOptions{}, readBody)
+
+		var statusErr *StatusError
+		switch {
+		case err == nil:
+			h.robots, h.robotsExpires = parseRobots(doc.Body, robotsAgent(f.userAgent)), time.Now().Add(24*time.Hour)
+		case errors.As(err, &statusErr) && statusErr.StatusCode >= 400 && statusErr.StatusCode < 500:
+			h.robots, h.robotsExpires = &robotsPolicy{}, time.Now().Add(24*time.Hour)
+		case ctx.Err() != nil:
+			return ctx.Err()
+		default:
+			h.robots, h.robotsExpires = &robotsPolicy{}, time.Now().Add(time.Hour)
+		}
+
+		h.mu.Lock()
+		h.crawlDelay = h.robots.crawlDelay
+		h.mu.Unlock()
+	}
+
+	if !h.robots.allowed(target.RequestURI()) {
+		return &DisallowedError{URL: target.String()}
+	}
+	return nil
+}
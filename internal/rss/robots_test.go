@@ -0,0 +1,187 @@
+package rss
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRobotsMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"/", "/feed", true},
+		{"/feed", "/feed", true},
+		{"/feed", "/feed.xml", true},
+		{"/feed", "/feeds/all", true},
+		{"/feed", "/blog/feed", false},
+		{"/feed/", "/feed", false},
+		{"/*.xml", "/blog/feed.xml", true},
+		{"/*.xml", "/blog/feed.xml?page=2", true},
+		{"/*.xml", "/blog/feed", false},
+		{"/*.xml$", "/blog/feed.xml", true},
+		{"/*.xml$", "/blog/feed.xml?page=2", false},
+		{"/feed$", "/feed", true},
+		{"/feed$", "/feed/", false},
+		{"/*/feed/*", "/blog/feed/rss", true},
+		{"/*/feed/*", "/feed/rss", false},
+		{"/private*", "/private", true},
+		{"*", "/anything", true},
+		{"/a*b*c$", "/a-b-c", true},
+		{"/a*b*c$", "/a-b-c-d", false},
+		{"/a*c$", "/abcc", true},
+	}
+
+	for _, test := range tests {
+		if got := robotsMatch(test.pattern, test.path); got != test.want {
+			t.Errorf("robotsMatch(%q, %q) = %t, want %t", test.pattern, test.path, got, test.want)
+		}
+	}
+}
+
+func TestParseRobots(t *testing.T) {
+	tests := []struct {
+		name    string
+		robots  string
+		allowed []string
+		denied  []string
+	}{
+		{
+			name:    "no rules",
+			robots:  "",
+			allowed: []string{"/", "/feed"},
+		},
+		{
+			name:    "wildcard group",
+			robots:  "User-agent: *\nDisallow: /private\n",
+			allowed: []string{"/feed"},
+			denied:  []string{"/private", "/private/feed"},
+		},
+		{
+			name:    "specific group replaces the wildcard",
+			robots:  "User-agent: *\nDisallow: /\n\nUser-agent: gator\nDisallow: /private\n",
+			allowed: []string{"/feed"},
+			denied:  []string{"/private"},
+		},
+		{
+			name:    "agent matched ignoring case",
+			robots:  "User-agent: *\nDisallow: /\n\nUser-agent: Gator\nAllow: /\n",
+			allowed: []string{"/feed"},
+		},
+		{
+			name:    "substring of the agent isn't the agent",
+			robots:  "User-agent: a\nDisallow: /\n\nUser-agent: gat\nDisallow: /\n",
+			allowed: []string{"/feed"},
+		},
+		{
+			name:    "longer agent isn't the agent",
+			robots:  "User-agent: gatorbot\nDisallow: /\n",
+			allowed: []string{"/feed"},
+		},
+		{
+			name:    "empty user-agent is ignored",
+			robots:  "User-agent:\nDisallow: /\n",
+			allowed: []string{"/feed"},
+		},
+		{
+			name:    "group with several agents",
+			robots:  "User-agent: other\nUser-agent: gator\nDisallow: /feed\n\nUser-agent: *\nDisallow: /\n",
+			allowed: []string{"/blog"},
+			denied:  []string{"/feed"},
+		},
+		{
+			name:    "groups naming the agent are merged",
+			robots:  "User-agent: gator\nDisallow: /a\n\nUser-agent: *\nDisallow: /b\n\nUser-agent: gator\nDisallow: /c\n",
+			allowed: []string{"/b"},
+			denied:  []string{"/a", "/c"},
+		},
+		{
+			name:    "longest match wins",
+			robots:  "User-agent: *\nDisallow: /blog\nAllow: /blog/feed\n",
+			allowed: []string{"/blog/feed", "/blog/feed.xml"},
+			denied:  []string{"/blog", "/blog/post"},
+		},
+		{
+			name:    "longest match wins over order",
+			robots:  "User-agent: *\nAllow: /blog\nDisallow: /blog/drafts\n",
+			allowed: []string{"/blog/post"},
+			denied:  []string{"/blog/drafts/1"},
+		},
+		{
+			name:    "allow wins a tie",
+			robots:  "User-agent: *\nDisallow: /feed\nAllow: /feed\n",
+			allowed: []string{"/feed"},
+		},
+		{
+			name:    "wildcard and anchored patterns",
+			robots:  "User-agent: *\nDisallow: /*.php$\nDisallow: /*?session=\n",
+			allowed: []string{"/index.php?page=2", "/feed"},
+			denied:  []string{"/index.php", "/feed?session=1"},
+		},
+		{
+			name:    "empty disallow allows everything",
+			robots:  "User-agent: *\nDisallow:\n",
+			allowed: []string{"/", "/feed"},
+		},
+		{
+			name:    "comments and odd spacing",
+			robots:  "# robots\nUSER-AGENT :  *   # everyone\n  disallow: /private # keep out\n",
+			allowed: []string{"/feed"},
+			denied:  []string{"/private"},
+		},
+	}
+
+	for _, test := range tests {
+		policy := parseRobots([]byte(test.robots), "gator")
+		for _, path := range test.allowed {
+			if !policy.allowed(path) {
+				t.Errorf("%s: %s is disallowed, want it allowed", test.name, path)
+			}
+		}
+		for _, path := range test.denied {
+			if policy.allowed(path) {
+				t.Errorf("%s: %s is allowed, want it disallowed", test.name, path)
+			}
+		}
+	}
+}
+
+func TestParseRobotsCrawlDelay(t *testing.T) {
+	tests := []struct {
+		name   string
+		robots string
+		want   time.Duration
+	}{
+		{"none", "User-agent: *\nDisallow: /private\n", 0},
+		{"seconds", "User-agent: *\nCrawl-delay: 5\n", 5 * time.Second},
+		{"fraction", "User-agent: *\nCrawl-delay: 0.5\n", 500 * time.Millisecond},
+		{"invalid", "User-agent: *\nCrawl-delay: soon\n", 0},
+		{"negative", "User-agent: *\nCrawl-delay: -3\n", 0},
+		{"specific group", "User-agent: *\nCrawl-delay: 10\n\nUser-agent: gator\nCrawl-delay: 2\n", 2 * time.Second},
+		{"other agent", "User-agent: otherbot\nCrawl-delay: 10\n", 0},
+	}
+
+	for _, test := range tests {
+		if got := parseRobots([]byte(test.robots), "gator").crawlDelay; got != test.want {
+			t.Errorf("%s: crawl delay = %s, want %s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestRobotsAgent(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		want      string
+	}{
+		{"gator/1.0 (RSS feed aggregator)", "gator"},
+		{"Gator", "gator"},
+		{" MyReader/2.1", "myreader"},
+	}
+
+	for _, test := range tests {
+		if got := robotsAgent(test.userAgent); got != test.want {
+			t.Errorf("robotsAgent(%q) = %q, want %q", test.userAgent, got, test.want)
+		}
+	}
+}
@@ -1,57 +1,358 @@
 package rss
 
 import (
+	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"html"
 	"io"
-	"net/http"
+	"strings"
+	"time"
 )
 
 type Feed struct {
-	Channel struct {
-		Title       string `xml:"title"`
-		Link        string `xml:"link"`
-		Description string `xml:"description"`
-		Item        []Item `xml:"item"`
-	} `xml:"channel"`
+	Channel Channel `xml:"channel"`
+
+	// ETag and LastModified are the cache validators sent by the server,
+	// they should be passed back on the next fetch of the same feed.
+	ETag         string `xml:"-"`
+	LastModified string `xml:"-"`
+	// StatusCode is the HTTP status of the response the feed was read from
+	StatusCode int `xml:"-"`
+	// MovedTo is the URL the feed permanently redirected to, empty when it
+	// wasn't moved
+	MovedTo string `xml:"-"`
+	// Expires is until when the response is fresh according to its
+	// Cache-Control or Expires headers, zero when it doesn't say
+	Expires time.Time `xml:"-"`
+}
+
+// Validators are the HTTP cache validators of a previous fetch, used to make
+// the request conditional. Empty values are not sent.
+type Validators struct {
+	ETag         string
+	LastModified string
+}
+
+// ErrNotModified is returned by FetchFeed when the server answered
+// 304 Not Modified to a conditional request.
+var ErrNotModified = errors.New("feed not modified")
+
+// StatusError is returned when the server answers with a status other than
+// 2xx or 304, the body of such responses is never parsed.
+type StatusError struct {
+	StatusCode int
+	Status     string
+	// RetryAfter is when the server asked to be retried, zero when it didn't
+	RetryAfter time.Time
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected response status: %s", e.Status)
+}
+
+type Channel struct {
+	Title string `xml:"title"`
+	Link  string `xml:"-"`
+	// Links collects every <link> of an RSS channel, atom:link included,
+	// Link is set to the first one with a value.
+	Links       []string `xml:"link"`
+	Description string   `xml:"description"`
+	Language    string   `xml:"language"`
+	Image       Image    `xml:"image"`
+	Generator   string   `xml:"generator"`
+	TTL         string   `xml:"ttl"`
+	SkipHours   []string `xml:"skipHours>hour"`
+	SkipDays    []string `xml:"skipDays>day"`
+	Item        []Item   `xml:"item"`
+	// Base is the xml:base of the channel, relative URLs are resolved
+	// against it
+	Base string `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+}
+
+// Image is the channel image or icon. Href is set by itunes:image, which
+// shares the element name with the RSS image.
+type Image struct {
+	URL   string `xml:"url"`
+	Title string `xml:"title"`
+	Link  string `xml:"link"`
+	Href  string `xml:"href,attr"`
+}
+
+// ImageURL returns the URL of the channel image, if any.
+func (c Channel) ImageURL() string {
+	if c.Image.URL != "" {
+		return strings.TrimSpace(c.Image.URL)
+	}
+	return strings.TrimSpace(c.Image.Href)
 }
 
 type Item struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
+	Title string `xml:"title"`
+	Link  string `xml:"-"`
+	// Links collects every <link> of an RSS item like Channel.Links, Link is
+	// set to the first one with a value.
+	Links       []string `xml:"link"`
+	Description string   `xml:"description"`
+	// Content is the full text of the item, from content:encoded or the
+	// Atom and JSON Feed content
+	Content string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	PubDate string `xml:"pubDate"`
+	Updated string `xml:"updated"`
+	Guid    string `xml:"guid"`
+	// Author is the dc:creator of the item, falling back to its author
+	// which RSS defines as an email address
+	Author     string   `xml:"author"`
+	DcCreator  string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Categories []string `xml:"category"`
+	DcDate     string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+	// the attached media, read through Media, ImageURL and Episode
+	Enclosures     []RSSEnclosure   `xml:"enclosure"`
+	MediaContent   []MediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaGroup     []MediaContent   `xml:"http://search.yahoo.com/mrss/ group>content"`
+	MediaThumbnail []MediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	ItunesDuration string           `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	ItunesEpisode  string           `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd episode"`
+	ItunesImage    Image            `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+	// Base is the xml:base of the item
+	Base string `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+}
+
+// Identity returns the key identifying the item within its feed: the guid
+// (or Atom id), else the link, else a hash of the title and description.
+func (i Item) Identity() string {
+	if guid := strings.TrimSpace(i.Guid); guid != "" {
+		return guid
+	}
+	if link := strings.TrimSpace(i.Link); link != "" {
+		return link
+	}
+	sum := sha256.Sum256([]byte(i.Title + "\n" + i.Description))
+	return "sha256:" + hex.EncodeToString(sum[:])
 }
 
-func FetchFeed(_ context.Context, feedURL string) (*Feed, error) {
-	response, err := http.Get(feedURL)
+// FetchFeed fetches and parses the feed at feedURL, making the request
+// conditional on the validators of a previous fetch. Along with
+// ErrNotModified it returns a Feed without a channel that still tells
+// whether the feed moved.
+func (f *Fetcher) FetchFeed(ctx context.Context, feedURL string, validators Validators) (*Feed, error) {
+	var feed *Feed
+	doc, err := f.fetchDocument(ctx, feedURL, requestOptions{validators: validators}, func(doc *document, body io.Reader) error {
+		var err error
+		feed, err = parseFeed(body, doc.Header.Get("Content-Type"), f.maxItems)
+		return err
+	})
+	if errors.Is(err, ErrNotModified) {
+		return &Feed{StatusCode: doc.StatusCode, MovedTo: doc.MovedTo}, err
+	}
 	if err != nil {
 		return nil, err
 	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			fmt.Printf("failed closing response body: %s\n", err)
+
+	finishFeed(feed, doc)
+	return feed, nil
+}
+
+// finishFeed fills in what the response tells about a parsed feed and
+// normalizes its items.
+func finishFeed(feed *Feed, doc *document) {
+	feed.StatusCode = doc.StatusCode
+	feed.MovedTo = doc.MovedTo
+	feed.ETag = doc.Header.Get("ETag")
+	feed.LastModified = doc.Header.Get("Last-Modified")
+	feed.Expires = cacheExpiry(doc.Header, time.Now())
+
+	for i, item := range feed.Channel.Item {
+		feed.Channel.Item[i].Author = firstNonEmpty(item.DcCreator, item.Author)
+		feed.Channel.Item[i].Categories = uniqueCategories(item.Categories)
+	}
+	feed.resolveURLs(doc.URL)
+}
+
+// uniqueCategories trims the categories and drops empty and repeated ones,
+// which are compared case-insensitively.
+func uniqueCategories(categories []string) []string {
+	var unique []string
+	seen := make(map[string]bool)
+	for _, category := range categories {
+		category = strings.TrimSpace(category)
+		if category == "" || seen[strings.ToLower(category)] {
+			continue
 		}
-	}(response.Body)
+		seen[strings.ToLower(category)] = true
+		unique = append(unique, category)
+	}
+	return unique
+}
 
-	responseBody, err := io.ReadAll(response.Body)
-	if err != nil {
-		return nil, err
+// errItemLimit stops decoding a document once enough items were read.
+var errItemLimit = errors.New("item limit reached")
+
+// limitedList collects repeated elements up to limit, zero meaning no limit,
+// and aborts decoding with errItemLimit at the next one so the rest of the
+// document isn't even read.
+type limitedList[T any] struct {
+	items []T
+	limit int
+}
+
+func (l *limitedList[T]) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	if l.limit > 0 && len(l.items) >= l.limit {
+		return errItemLimit
 	}
+	var item T
+	if err := decoder.DecodeElement(&item, &start); err != nil {
+		return err
+	}
+	l.items = append(l.items, item)
+	return nil
+}
 
-	var feed *Feed
-	err = xml.Unmarshal(responseBody, &feed)
+// rssDocument is an RSS 2.0 document, the items are decoded apart from the
+// channel to stop after the first maxItems.
+type rssDocument struct {
+	Base    string `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	Channel struct {
+		Channel
+		Title rssTitle             `xml:"title"`
+		Items limitedList[rssItem] `xml:"item"`
+	} `xml:"channel"`
+}
+
+// rssItem is an item of an RSS 2.0 document, its title shadows Item.Title.
+type rssItem struct {
+	Item
+	Title rssTitle `xml:"title"`
+}
+
+// rssTitle is the title of an RSS channel or item. encoding/xml matches
+// extensions like itunes:title and media:title against it as well, and the
+// last of them would win, so only a title without a namespace or in the RSS
+// 1.0 namespace is kept.
+type rssTitle string
+
+func (t *rssTitle) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	var title string
+	if err := decoder.DecodeElement(&title, &start); err != nil {
+		return err
+	}
+	if start.Name.Space == "" || start.Name.Space == "http://purl.org/rss/1.0/" {
+		*t = rssTitle(title)
+	}
+	return nil
+}
+
+// parseFeed detects the format of the document, JSON Feed by its content type
+// or first bytes and XML formats by the root element, and normalizes it into
+// a Feed. XML in any charset is transcoded to UTF-8 and decoded while it's
+// read, and reading stops after maxItems items, zero meaning all of them, so
+// channel elements following the items may be missed in long feeds.
+func parseFeed(body io.Reader, contentType string, maxItems int) (*Feed, error) {
+	buffered := bufio.NewReader(body)
+	head, _ := buffered.Peek(512)
+	if isJSONFeed(head, contentType) {
+		var feed *jsonFeed
+		if err := json.NewDecoder(buffered).Decode(&feed); err != nil {
+			return nil, fmt.Errorf("failed parsing JSON feed: %s", err)
+		}
+		// any JSON object decodes, the version tells a feed from other JSON
+		if feed == nil || !strings.HasPrefix(feed.Version, "https://jsonfeed.org/version/") {
+			return nil, errors.New("not a JSON feed, the version is missing")
+		}
+		if maxItems > 0 && len(feed.Items) > maxItems {
+			feed.Items = feed.Items[:maxItems]
+		}
+		return feed.toFeed(), nil
+	}
+
+	decoder := xml.NewDecoder(toUTF8(buffered, head, contentType))
+	decoder.CharsetReader = utf8CharsetReader
+	root, err := rootElement(decoder)
 	if err != nil {
 		return nil, err
 	}
 
-	for i, item := range feed.Channel.Item {
-		feed.Channel.Item[i].Title = html.UnescapeString(item.Title)
-		feed.Channel.Item[i].Description = html.UnescapeString(item.Description)
+	switch root.Name.Local {
+	case "rss":
+		var doc rssDocument
+		doc.Channel.Items.limit = maxItems
+		if err := decodeFeed(decoder, &doc, root); err != nil {
+			return nil, err
+		}
+		feed := &Feed{Channel: doc.Channel.Channel}
+		feed.Channel.Title = string(doc.Channel.Title)
+		feed.Channel.Link = firstLink(feed.Channel.Links)
+		for _, item := range doc.Channel.Items.items {
+			item.Item.Title = string(item.Title)
+			item.Item.Link = firstLink(item.Links)
+			feed.Channel.Item = append(feed.Channel.Item, item.Item)
+		}
+		unescapeItems(feed.Channel.Item)
+		feed.Channel.Base = joinXMLBase(doc.Base, feed.Channel.Base)
+		return feed, nil
+	case "feed":
+		var feed atomFeed
+		feed.Entries.limit = maxItems
+		if err := decodeFeed(decoder, &feed, root); err != nil {
+			return nil, err
+		}
+		return feed.toFeed(), nil
+	case "RDF":
+		var feed rdfFeed
+		feed.Items.limit = maxItems
+		if err := decodeFeed(decoder, &feed, root); err != nil {
+			return nil, err
+		}
+		return feed.toFeed(), nil
+	default:
+		return nil, fmt.Errorf("unsupported feed format: <%s>", root.Name.Local)
+	}
+}
+
+// firstLink returns the first link with a value. atom:link elements share the
+// element name with the RSS link, but keep their URL in href.
+func firstLink(links []string) string {
+	for _, link := range links {
+		if strings.TrimSpace(link) != "" {
+			return strings.TrimSpace(link)
+		}
 	}
+	return ""
+}
 
-	return feed, nil
+// unescapeItems decodes the HTML entities in the titles and descriptions of
+// RSS items. Atom and JSON Feed mark whether their text is HTML, so they are
+// left as they are.
+func unescapeItems(items []Item) {
+	for i := range items {
+		items[i].Title = html.UnescapeString(items[i].Title)
+		items[i].Description = html.UnescapeString(items[i].Description)
+	}
+}
+
+// decodeFeed decodes the document from its root element, reaching the item
+// limit isn't an error.
+func decodeFeed(decoder *xml.Decoder, feed any, root xml.StartElement) error {
+	err := decoder.DecodeElement(feed, &root)
+	if err != nil && !errors.Is(err, errItemLimit) {
+		return err
+	}
+	return nil
+}
+
+func rootElement(decoder *xml.Decoder) (xml.StartElement, error) {
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return xml.StartElement{}, fmt.Errorf("failed reading feed document: %s", err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start, nil
+		}
+	}
 }
@@ -0,0 +1,237 @@
+package rss
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// podcastFeed generates an RSS podcast feed with the given number of
+// episodes, newest first, each with show notes and the usual iTunes tags.
+func podcastFeed(episodes int) []byte {
+	notes := strings.Repeat("<p>In this episode we talk about feeds, parsers &amp; the <a href=\"/links\">links</a> we found.</p>", 12)
+	published := time.Date(2024, time.June, 1, 6, 0, 0, 0, time.UTC)
+
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+<channel>
+<title>Benchmark Podcast</title>
+<link>https://podcast.example.com/</link>
+<description>A generated podcast</description>
+<itunes:author>Gator</itunes:author>
+<itunes:image href="https://podcast.example.com/cover.jpg"/>
+`)
+	for i := episodes; i > 0; i-- {
+		fmt.Fprintf(&b, `<item>
+<title>Episode %[1]d</title>
+<itunes:title>Episode %[1]d</itunes:title>
+<itunes:episode>%[1]d</itunes:episode>
+<itunes:duration>01:02:03</itunes:duration>
+<link>https://podcast.example.com/episodes/%[1]d</link>
+<guid isPermaLink="false">podcast-episode-%[1]d</guid>
+<pubDate>%[2]s</pubDate>
+<enclosure url="https://cdn.example.com/episodes/%[1]d.mp3" length="%[3]d" type="audio/mpeg"/>
+<description><![CDATA[%[4]s]]></description>
+<content:encoded><![CDATA[%[4]s]]></content:encoded>
+</item>
+`, i, published.AddDate(0, 0, -7*(episodes-i)).Format(time.RFC1123Z), 50_000_000+i, notes)
+	}
+	b.WriteString("</channel>\n</rss>\n")
+	return b.Bytes()
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count += int64(n)
+	return n, err
+}
+
+func TestParseFeedMaxItems(t *testing.T) {
+	fixture := podcastFeed(50)
+	tests := []struct {
+		maxItems int
+		want     int
+	}{
+		{0, 50},
+		{10, 10},
+		{50, 50},
+		{100, 50},
+	}
+
+	for _, test := range tests {
+		reader := &countingReader{reader: bytes.NewReader(fixture)}
+		feed, err := parseFeed(reader, "application/rss+xml", test.maxItems)
+		if err != nil {
+			t.Fatalf("parseFeed with maxItems %d failed: %s", test.maxItems, err)
+		}
+		if got := len(feed.Channel.Item); got != test.want {
+			t.Errorf("parseFeed with maxItems %d returned %d items, want %d", test.maxItems, got, test.want)
+		}
+		if feed.Channel.Title != "Benchmark Podcast" {
+			t.Errorf("parseFeed with maxItems %d returned the title %q", test.maxItems, feed.Channel.Title)
+		}
+		if first := feed.Channel.Item[0].Title; first != "Episode 50" {
+			t.Errorf("parseFeed with maxItems %d returned %q first, want the newest episode", test.maxItems, first)
+		}
+		if test.want < 50 && reader.count >= int64(len(fixture)) {
+			t.Errorf("parseFeed with maxItems %d read the whole feed", test.maxItems)
+		}
+	}
+}
+
+func TestParseFeedTitles(t *testing.T) {
+	tests := []struct {
+		name    string
+		feed    string
+		channel string
+		item    string
+	}{
+		{"extension titles after the title", `<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd" xmlns:media="http://search.yahoo.com/mrss/">
+<channel><title>Show</title><itunes:title>Show (iTunes)</itunes:title>
+<item><title>Episode 1: Pilot</title><itunes:title>Pilot</itunes:title><media:title>Pilot video</media:title></item>
+</channel></rss>`, "Show", "Episode 1: Pilot"},
+		{"extension titles before the title", `<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">
+<channel><itunes:title>Show (iTunes)</itunes:title><title>Show</title>
+<item><itunes:title>Pilot</itunes:title><title>Episode 1: Pilot</title></item>
+</channel></rss>`, "Show", "Episode 1: Pilot"},
+		{"only an extension title", `<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">
+<channel><title>Show</title><item><itunes:title>Pilot</itunes:title></item></channel></rss>`, "Show", ""},
+		{"RSS 1.0", `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<channel><title>News</title></channel>
+<item rdf:about="https://example.com/1"><title>First</title><dc:title>Other</dc:title></item>
+</rdf:RDF>`, "News", "First"},
+	}
+
+	for _, test := range tests {
+		feed, err := parseFeed(strings.NewReader(test.feed), "application/xml", 0)
+		if err != nil {
+			t.Errorf("%s: parseFeed failed: %s", test.name, err)
+			continue
+		}
+		if feed.Channel.Title != test.channel {
+			t.Errorf("%s: channel title = %q, want %q", test.name, feed.Channel.Title, test.channel)
+		}
+		if len(feed.Channel.Item) != 1 {
+			t.Errorf("%s: parseFeed returned %d items, want 1", test.name, len(feed.Channel.Item))
+			continue
+		}
+		if got := feed.Channel.Item[0].Title; got != test.item {
+			t.Errorf("%s: item title = %q, want %q", test.name, got, test.item)
+		}
+	}
+}
+
+func TestParseFeedJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		ok          bool
+	}{
+		{"JSON Feed 1.1", `{"version": "https://jsonfeed.org/version/1.1", "title": "Blog", "items": []}`, "application/feed+json", true},
+		{"JSON Feed 1.0 sniffed", `{"version": "https://jsonfeed.org/version/1", "title": "Blog", "items": []}`, "text/plain", true},
+		{"API response", `{"status": "ok", "items": [{"id": 1}]}`, "application/json", false},
+		{"unknown version", `{"version": "2.0", "title": "Blog"}`, "application/json", false},
+		{"null", `null`, "application/json", false},
+	}
+
+	for _, test := range tests {
+		_, err := parseFeed(strings.NewReader(test.body), test.contentType, 0)
+		if ok := err == nil; ok != test.ok {
+			t.Errorf("%s: parseFeed error = %v, want ok %t", test.name, err, test.ok)
+		}
+	}
+}
+
+func TestParseFeedUnescape(t *testing.T) {
+	rss := `<rss version="2.0"><channel><title>Blog</title>
+<item><title>1 &amp;lt; 2</title><description>a &amp;amp; b</description></item>
+</channel></rss>`
+	feed, err := parseFeed(strings.NewReader(rss), "application/rss+xml", 0)
+	if err != nil {
+		t.Fatalf("parseFeed failed: %s", err)
+	}
+	if item := feed.Channel.Item[0]; item.Title != "1 < 2" || item.Description != "a & b" {
+		t.Errorf("RSS item = %q, %q, want its entities decoded", item.Title, item.Description)
+	}
+
+	jsonFeed := `{"version": "https://jsonfeed.org/version/1.1", "title": "Blog",
+"items": [{"id": "1", "title": "1 &lt; 2", "content_text": "1 &lt; 2"}]}`
+	feed, err = parseFeed(strings.NewReader(jsonFeed), "application/feed+json", 0)
+	if err != nil {
+		t.Fatalf("parseFeed failed: %s", err)
+	}
+	if item := feed.Channel.Item[0]; item.Title != "1 &lt; 2" || item.Description != "1 &lt; 2" || item.Content != "1 &lt; 2" {
+		t.Errorf("JSON Feed item = %q, %q, %q, want its plain text as it is", item.Title, item.Description, item.Content)
+	}
+}
+
+func TestParseFeedLinks(t *testing.T) {
+	tests := []struct {
+		name string
+		feed string
+		want string
+	}{
+		{"atom:link after the link", `<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom"><channel><title>Blog</title>
+<item><link>https://example.com/a</link><atom:link rel="replies" href="https://example.com/a/comments"/></item>
+</channel></rss>`, "https://example.com/a"},
+		{"atom:link before the link", `<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom"><channel><title>Blog</title>
+<item><atom:link rel="replies" href="https://example.com/a/comments"/><link> https://example.com/a </link></item>
+</channel></rss>`, "https://example.com/a"},
+		{"RSS 1.0", `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/" xmlns:atom="http://www.w3.org/2005/Atom">
+<channel><title>News</title></channel>
+<item rdf:about="https://example.com/1"><link>https://example.com/1</link><atom:link rel="self" href="https://example.com/1.xml"/></item>
+</rdf:RDF>`, "https://example.com/1"},
+	}
+
+	for _, test := range tests {
+		feed, err := parseFeed(strings.NewReader(test.feed), "application/xml", 0)
+		if err != nil {
+			t.Errorf("%s: parseFeed failed: %s", test.name, err)
+			continue
+		}
+		if len(feed.Channel.Item) != 1 {
+			t.Errorf("%s: parseFeed returned %d items, want 1", test.name, len(feed.Channel.Item))
+			continue
+		}
+		if got := feed.Channel.Item[0].Link; got != test.want {
+			t.Errorf("%s: item link = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+// BenchmarkParseFeed parses a multi-megabyte podcast feed with all of its
+// episodes and with the default limit, which stops reading the body early.
+func BenchmarkParseFeed(b *testing.B) {
+	fixture := podcastFeed(5000)
+	for _, bench := range []struct {
+		name     string
+		maxItems int
+	}{
+		{"unlimited", 0},
+		{"limited", 500},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			var read int64
+			for b.Loop() {
+				reader := &countingReader{reader: bytes.NewReader(fixture)}
+				if _, err := parseFeed(reader, "application/rss+xml", bench.maxItems); err != nil {
+					b.Fatal(err)
+				}
+				read += reader.count
+			}
+			b.ReportMetric(float64(read)/float64(b.N), "read-B/op")
+		})
+	}
+}
@@ -0,0 +1,98 @@
+// Package schedule computes when a feed should be fetched next
+package schedule
+
+import (
+	"time"
+)
+
+// defaultInterval is used when there are not enough posts to tell how often
+// a feed publishes.
+const defaultInterval = time.Hour
+
+// Input is what is known about a feed after fetching it.
+type Input struct {
+	// PostDates are the publish dates of the most recent posts, newest first
+	PostDates []time.Time
+	// TTL is the RSS <ttl>, how long the feed may be cached
+	TTL time.Duration
+	// Expires is when the HTTP response stops being fresh according to its
+	// Cache-Control or Expires headers
+	Expires time.Time
+	// SkipHours (0-23, GMT) and SkipDays are the RSS skipHours and skipDays,
+	// times at which the feed asks not to be fetched
+	SkipHours []int
+	SkipDays  []time.Weekday
+}
+
+// Next returns when the feed should be fetched again: about twice per average
+// gap between its recent posts, but not before the TTL or the HTTP expiry,
+// clamped between minInterval and maxInterval and moved out of the skipped
+// hours and days.
+func Next(now time.Time, in Input, minInterval, maxInterval time.Duration) time.Time {
+	interval := postingInterval(in.PostDates) / 2
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	interval = max(interval, in.TTL)
+	if in.Expires.After(now) {
+		interval = max(interval, in.Expires.Sub(now))
+	}
+	interval = min(max(interval, minInterval), maxInterval)
+
+	return skip(now.Add(interval).UTC(), in.SkipHours, in.SkipDays)
+}
+
+// postingInterval is the average gap between the given publish dates, zero
+// when there are less than two.
+func postingInterval(dates []time.Time) time.Duration {
+	if len(dates) < 2 {
+		return 0
+	}
+	newest, oldest := dates[0], dates[0]
+	for _, date := range dates[1:] {
+		newest = maxTime(newest, date)
+		oldest = minTime(oldest, date)
+	}
+	return newest.Sub(oldest) / time.Duration(len(dates)-1)
+}
+
+// skip moves next forward to the start of the first hour that is neither a
+// skipped hour nor on a skipped day. A feed skipping every hour of the week
+// is fetched at next regardless.
+func skip(next time.Time, hours []int, days []time.Weekday) time.Time {
+	if len(hours) == 0 && len(days) == 0 {
+		return next
+	}
+
+	skippedHours := make(map[int]bool, len(hours))
+	for _, hour := range hours {
+		skippedHours[hour] = true
+	}
+	skippedDays := make(map[time.Weekday]bool, len(days))
+	for _, day := range days {
+		skippedDays[day] = true
+	}
+
+	candidate := next
+	for range 7 * 24 {
+		if !skippedHours[candidate.Hour()] && !skippedDays[candidate.Weekday()] {
+			return candidate
+		}
+		candidate = candidate.Truncate(time.Hour).Add(time.Hour)
+	}
+	return next
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
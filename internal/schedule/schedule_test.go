@@ -0,0 +1,114 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+// now is a Monday morning.
+var now = time.Date(2024, time.March, 4, 10, 30, 0, 0, time.UTC)
+
+// every returns count publish dates spaced gap apart, newest first.
+func every(gap time.Duration, count int) []time.Time {
+	var dates []time.Time
+	for i := range count {
+		dates = append(dates, now.Add(-time.Duration(i)*gap))
+	}
+	return dates
+}
+
+func TestNext(t *testing.T) {
+	const minInterval, maxInterval = 15 * time.Minute, 24 * time.Hour
+	tests := []struct {
+		name string
+		in   Input
+		want time.Time
+	}{
+		{"no posts", Input{}, now.Add(time.Hour)},
+		{"single post", Input{PostDates: every(time.Hour, 1)}, now.Add(time.Hour)},
+		{"half the posting interval", Input{PostDates: every(4*time.Hour, 5)}, now.Add(2 * time.Hour)},
+		{"clamped to the minimum", Input{PostDates: every(10*time.Minute, 5)}, now.Add(minInterval)},
+		{"clamped to the maximum", Input{PostDates: every(7*24*time.Hour, 3)}, now.Add(maxInterval)},
+		{"TTL floor", Input{PostDates: every(4*time.Hour, 5), TTL: 3 * time.Hour}, now.Add(3 * time.Hour)},
+		{"TTL below the interval", Input{PostDates: every(4*time.Hour, 5), TTL: time.Hour}, now.Add(2 * time.Hour)},
+		{"TTL beyond the maximum", Input{TTL: 48 * time.Hour}, now.Add(maxInterval)},
+		{"Expires floor", Input{Expires: now.Add(5 * time.Hour)}, now.Add(5 * time.Hour)},
+		{"Expires in the past", Input{Expires: now.Add(-5 * time.Hour)}, now.Add(time.Hour)},
+		{"Expires and TTL", Input{TTL: 2 * time.Hour, Expires: now.Add(90 * time.Minute)}, now.Add(2 * time.Hour)},
+		{"skipped hours", Input{SkipHours: []int{11, 12}}, time.Date(2024, time.March, 4, 13, 0, 0, 0, time.UTC)},
+		{"skipped day", Input{TTL: 24 * time.Hour, SkipDays: []time.Weekday{time.Tuesday}}, time.Date(2024, time.March, 6, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		got := Next(now, test.in, minInterval, maxInterval)
+		if !got.Equal(test.want) {
+			t.Errorf("%s: Next() = %s, want %s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestNextUTC(t *testing.T) {
+	berlin := time.FixedZone("CET", 60*60)
+	got := Next(now.In(berlin), Input{}, time.Minute, time.Hour)
+	if got.Location() != time.UTC {
+		t.Errorf("Next() = %s, want it in UTC", got)
+	}
+	if want := now.Add(time.Hour); !got.Equal(want) {
+		t.Errorf("Next() = %s, want %s", got, want)
+	}
+}
+
+func TestPostingInterval(t *testing.T) {
+	tests := []struct {
+		name  string
+		dates []time.Time
+		want  time.Duration
+	}{
+		{"none", nil, 0},
+		{"one", every(time.Hour, 1), 0},
+		{"regular", every(6*time.Hour, 4), 6 * time.Hour},
+		{"irregular", []time.Time{now, now.Add(-time.Hour), now.Add(-5 * time.Hour)}, 150 * time.Minute},
+		{"unordered", []time.Time{now.Add(-2 * time.Hour), now, now.Add(-4 * time.Hour)}, 2 * time.Hour},
+		{"same time", []time.Time{now, now}, 0},
+	}
+
+	for _, test := range tests {
+		if got := postingInterval(test.dates); got != test.want {
+			t.Errorf("%s: postingInterval() = %s, want %s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestSkip(t *testing.T) {
+	// Sunday evening
+	sunday := time.Date(2024, time.March, 3, 22, 15, 0, 0, time.UTC)
+	var everyHour []int
+	for hour := range 24 {
+		everyHour = append(everyHour, hour)
+	}
+	tests := []struct {
+		name  string
+		next  time.Time
+		hours []int
+		days  []time.Weekday
+		want  time.Time
+	}{
+		{"nothing skipped", sunday, nil, nil, sunday},
+		{"hour not skipped", sunday, []int{3, 4}, nil, sunday},
+		{"day not skipped", sunday, nil, []time.Weekday{time.Saturday}, sunday},
+		{"skipped hour", sunday, []int{22}, nil, time.Date(2024, time.March, 3, 23, 0, 0, 0, time.UTC)},
+		{"hours past midnight", sunday, []int{22, 23, 0, 1}, nil, time.Date(2024, time.March, 4, 2, 0, 0, 0, time.UTC)},
+		{"skipped day", sunday, nil, []time.Weekday{time.Sunday}, time.Date(2024, time.March, 4, 0, 0, 0, 0, time.UTC)},
+		{"skipped hours and days", sunday, []int{22, 23, 0}, []time.Weekday{time.Monday}, time.Date(2024, time.March, 5, 1, 0, 0, 0, time.UTC)},
+		{"every hour skipped", sunday, everyHour, nil, sunday},
+		{"every day skipped", sunday, nil, []time.Weekday{
+			time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday,
+		}, sunday},
+	}
+
+	for _, test := range tests {
+		if got := skip(test.next, test.hours, test.days); !got.Equal(test.want) {
+			t.Errorf("%s: skip() = %s, want %s", test.name, got, test.want)
+		}
+	}
+}
@@ -8,7 +8,11 @@ import (
 	"gator/internal/core"
 	"gator/internal/database"
 	"gator/internal/handler"
+	"gator/internal/rss"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	_ "github.com/lib/pq"
 )
@@ -34,8 +38,35 @@ func main() {
 		}
 	}(db)
 
+	fetcher, err := rss.NewFetcher(rss.Options{
+		UserAgent:      config.HTTP.UserAgent,
+		ConnectTimeout: time.Duration(config.HTTP.ConnectTimeout),
+		ReadTimeout:    time.Duration(config.HTTP.ReadTimeout),
+		MaxBodySize:    config.HTTP.MaxBodySize,
+		MaxItems:       *config.HTTP.MaxItems,
+		Proxy:          config.HTTP.Proxy,
+		CABundle:       config.HTTP.CABundle,
+
+		HostConcurrency: config.HTTP.HostConcurrency,
+		HostDelay:       time.Duration(config.HTTP.HostDelay),
+		HostMaxWait:     time.Duration(config.HTTP.HostMaxWait),
+		Robots:          config.HTTP.Robots,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// after the first signal the default handling is restored, so a second
+	// Ctrl+C quits right away instead of waiting for the shutdown
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
 	dbQueries := database.New(db)
-	currentState := &core.State{Config: config, Db: dbQueries}
+	currentState := &core.State{Ctx: ctx, Config: config, Db: dbQueries, DbConn: db, Fetcher: fetcher}
 
 	commands := commands{commands: make(map[string]func(*core.State, core.Command) error)}
 	commands.register("help", func(s *core.State, _ core.Command) error {
@@ -51,11 +82,14 @@ func main() {
 	commands.register("users", handler.GetUsers)
 	commands.register("addfeed", middlewareLoggedIn(handler.AddFeed))
 	commands.register("feeds", handler.FetchFeeds)
+	commands.register("feed", handler.Feed)
 	commands.register("follow", middlewareLoggedIn(handler.FollowFeed))
 	commands.register("following", middlewareLoggedIn(handler.FeedFollowsForUser))
 	commands.register("unfollow", middlewareLoggedIn(handler.UnfollowFeed))
 	commands.register("agg", handler.AggregateFeeds)
 	commands.register("browse", middlewareLoggedIn(handler.Browse))
+	commands.register("post", handler.Post)
+	commands.register("download", middlewareLoggedIn(handler.Download))
 
 	args := os.Args
 	if len(args) < 2 {
@@ -71,7 +105,7 @@ func main() {
 
 func middlewareLoggedIn(handler func(s *core.State, cmd core.Command, user database.User) error) func(*core.State, core.Command) error {
 	return func(s *core.State, cmd core.Command) error {
-		user, err := s.Db.GetUser(context.Background(), s.Config.CurrentUserName)
+		user, err := s.Db.GetUser(s.Ctx, s.Config.CurrentUserName)
 		if err != nil {
 			return fmt.Errorf("failed to get current user: %w", err)
 		}
@@ -0,0 +1,71 @@
+-- name: GetDownloadCandidates :many
+-- Returns the enclosures of the feeds the user follows with the state of their
+-- download. Position ranks the posts with enclosures of a feed from the newest
+-- one on, only the newest max_keep of every feed and completed downloads are
+-- returned.
+WITH ranked AS (SELECT p.id,
+                       p.feed_id,
+                       p.title,
+                       p.published_at,
+                       ROW_NUMBER() OVER (PARTITION BY p.feed_id
+                           ORDER BY p.published_at DESC NULLS LAST, p.created_at DESC) AS position
+                FROM posts p
+                         JOIN feed_follows ff ON ff.feed_id = p.feed_id
+                WHERE ff.user_id = @user_id
+                  AND EXISTS (SELECT 1 FROM post_enclosures pe WHERE pe.post_id = p.id))
+SELECT e.post_id,
+       e.url,
+       e.mime_type,
+       r.title,
+       r.published_at,
+       r.position::INTEGER AS position,
+       f.id                AS feed_id,
+       f.name              AS feed_name,
+       f.url               AS feed_url,
+       d.id                AS download_id,
+       d.path,
+       d.state,
+       d.bytes,
+       d.sha256
+FROM ranked r
+         JOIN post_enclosures e ON e.post_id = r.id
+         JOIN feeds f ON f.id = r.feed_id
+         LEFT JOIN downloads d ON d.post_id = e.post_id AND d.url = e.url
+WHERE r.position <= @max_keep::INTEGER
+   OR d.state = 'complete'
+ORDER BY f.name, r.position, e.url;
+
+-- name: StartDownload :one
+INSERT INTO downloads (post_id, url, path, state)
+VALUES ($1, $2, $3, 'downloading')
+ON CONFLICT (post_id, url) DO UPDATE
+    SET path       = EXCLUDED.path,
+        state      = 'downloading',
+        last_error = NULL,
+        updated_at = NOW()
+RETURNING id;
+
+-- name: CompleteDownload :exec
+UPDATE downloads
+SET state        = 'complete',
+    bytes        = $2,
+    sha256       = $3,
+    last_error   = NULL,
+    completed_at = NOW(),
+    updated_at   = NOW()
+WHERE id = $1;
+
+-- name: FailDownload :exec
+-- Keeps a download that was cut short, it resumes from bytes on the next run.
+UPDATE downloads
+SET state      = 'partial',
+    bytes      = $2,
+    last_error = $3,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: RemoveDownload :exec
+UPDATE downloads
+SET state      = 'removed',
+    updated_at = NOW()
+WHERE id = $1;
@@ -1,11 +1,12 @@
 -- name: CreateFeed :one
-INSERT INTO feeds (id, name, url, user_id, created_at, updated_at)
+INSERT INTO feeds (id, name, url, original_url, user_id, created_at, updated_at)
 VALUES ($1,
         $2,
         $3,
         $4,
         $5,
-        $6)
+        $6,
+        $7)
 RETURNING *;
 
 -- name: GetFeedsWithUserName :many
@@ -48,13 +49,152 @@ WHERE user_id = $1
   AND feed_id = $2;
 
 -- name: MarkFeedFetched :execrows
+-- Marks the feed fetched and releases its lease.
 UPDATE feeds
-SET last_fetched_at = NOW(),
-    updated_at      = NOW()
+SET last_fetched_at  = NOW(),
+    lease_expires_at = NULL,
+    updated_at       = NOW()
 WHERE id = $1;
 
--- name: GetNextFeedToFetch :one
+-- name: ReleaseFeedLease :exec
+-- Releases the lease of a feed that wasn't fetched, so it's due again right away.
+UPDATE feeds
+SET lease_expires_at = NULL
+WHERE id = $1;
+
+-- name: RenewFeedLease :execrows
+-- Extends the lease of a feed by lease_seconds when its fetch starts, as long
+-- as it's still the lease taken until leased_until. Updates nothing when the
+-- lease ran out and another agg process has leased the feed since.
+UPDATE feeds
+SET lease_expires_at = NOW() + @lease_seconds::INTEGER * INTERVAL '1 second'
+WHERE id = @id
+  AND lease_expires_at = @leased_until;
+
+-- name: LeaseFeedsToFetch :many
+-- Claims the next feeds to fetch for lease_seconds. Feeds locked or leased by
+-- another agg process are skipped, expired leases of crashed ones are reclaimed.
+UPDATE feeds
+SET lease_expires_at = NOW() + @lease_seconds::INTEGER * INTERVAL '1 second'
+WHERE id IN (SELECT id
+             FROM feeds
+             WHERE (lease_expires_at IS NULL OR lease_expires_at < NOW())
+               AND (next_fetch_at IS NULL OR next_fetch_at <= NOW())
+               AND disabled_at IS NULL
+             ORDER BY next_fetch_at NULLS FIRST, last_fetched_at NULLS FIRST
+             LIMIT @batch_size FOR UPDATE SKIP LOCKED)
+RETURNING *;
+
+-- name: UpdateFeedCacheValidators :exec
+UPDATE feeds
+SET etag          = $2,
+    last_modified = $3,
+    updated_at    = NOW()
+WHERE id = $1;
+
+-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET title       = $2,
+    description = $3,
+    site_url    = $4,
+    language    = $5,
+    image_url   = $6,
+    generator   = $7,
+    updated_at  = NOW()
+WHERE id = $1;
+
+-- name: RecordFeedSuccess :exec
+-- Resets the failures of the feed and schedules its next fetch in
+-- next_fetch_seconds.
+UPDATE feeds
+SET consecutive_failures = 0,
+    last_error           = NULL,
+    last_status          = @last_status,
+    last_success_at      = NOW(),
+    next_fetch_at        = NOW() + @next_fetch_seconds::INTEGER * INTERVAL '1 second'
+WHERE id = @id;
+
+-- name: RecordFeedFailure :one
+-- Counts a failed fetch and postpones the feed by backoff_seconds, the feed is
+-- disabled once it has failed max_failures times in a row.
+UPDATE feeds
+SET consecutive_failures = consecutive_failures + 1,
+    last_error           = @last_error,
+    last_status          = @last_status,
+    next_fetch_at        = NOW() + @backoff_seconds::INTEGER * INTERVAL '1 second',
+    disabled_at          = CASE
+                               WHEN consecutive_failures + 1 >= @max_failures::INTEGER THEN NOW()
+        END
+WHERE id = @id
+RETURNING consecutive_failures, disabled_at;
+
+-- name: GetBrokenFeeds :many
 SELECT *
 FROM feeds
-ORDER BY last_fetched_at NULLS FIRST
-LIMIT 1;
\ No newline at end of file
+WHERE consecutive_failures > 0
+   OR disabled_at IS NOT NULL
+ORDER BY disabled_at NULLS LAST, consecutive_failures DESC;
+
+-- name: EnableFeed :execrows
+UPDATE feeds
+SET disabled_at          = NULL,
+    consecutive_failures = 0,
+    next_fetch_at        = NULL,
+    updated_at           = NOW()
+WHERE url = $1;
+
+-- name: RetireFeed :exec
+-- Disables a feed the server reported as gone for good.
+UPDATE feeds
+SET disabled_at   = NOW(),
+    last_error    = @last_error,
+    last_status   = @last_status,
+    next_fetch_at = NULL,
+    updated_at    = NOW()
+WHERE id = @id;
+
+-- name: PostponeFeed :exec
+-- Postpones the next fetch of a feed the server asked to retry later, that
+-- doesn't count as a failure.
+UPDATE feeds
+SET last_error    = @last_error,
+    last_status   = @last_status,
+    next_fetch_at = NOW() + @retry_seconds::INTEGER * INTERVAL '1 second'
+WHERE id = @id;
+
+-- name: RecordFeedRedirect :exec
+INSERT INTO feed_redirects (feed_id, from_url, to_url)
+VALUES ($1, $2, $3);
+
+-- name: UpdateFeedUrl :exec
+UPDATE feeds
+SET url          = $2,
+    original_url = $3,
+    updated_at   = NOW()
+WHERE id = $1;
+
+-- name: MergeFeed :exec
+-- Merges a feed that moved to the URL of another feed into that one: its
+-- followers follow the other feed, its posts missing there move over and the
+-- feed itself is disabled.
+WITH moved_follows AS (
+    INSERT INTO feed_follows (id, user_id, feed_id, created_at, updated_at)
+        SELECT gen_random_uuid(), user_id, @into_id::UUID, NOW(), NOW()
+        FROM feed_follows
+        WHERE feed_id = @from_id::UUID
+        ON CONFLICT (user_id, feed_id) DO NOTHING),
+     dropped_follows AS (
+         DELETE FROM feed_follows
+             WHERE feed_id = @from_id::UUID),
+     moved_posts AS (
+         UPDATE posts
+             SET feed_id = @into_id::UUID,
+                 updated_at = NOW()
+             WHERE feed_id = @from_id::UUID
+                 AND guid NOT IN (SELECT guid FROM posts WHERE feed_id = @into_id::UUID))
+UPDATE feeds
+SET disabled_at   = NOW(),
+    last_error    = @last_error,
+    next_fetch_at = NULL,
+    updated_at    = NOW()
+WHERE id = @from_id::UUID;
@@ -1,19 +1,167 @@
--- name: CreatePost :one
-INSERT INTO posts (title, url, description, published_at, feed_id, created_at, updated_at)
-VALUES ($1,
-        $2,
-        $3,
-        $4,
-        $5,
-        $6,
-        $7)
-RETURNING *;
+-- name: UpsertPost :one
+-- Inserts a new post or updates the stored one when the item changed, the
+-- replaced version is kept in post_revisions when a field shown in the history
+-- changed. url is the canonical link the post is compared by, a change of only
+-- the original_url isn't stored. The categories of the post are replaced by the
+-- given ones. Returns no rows when nothing but the categories changed. Items
+-- without a publish date fall back to the first-seen time.
+WITH previous AS (SELECT *
+                  FROM posts
+                  WHERE feed_id = @feed_id
+                    AND guid = @guid),
+     upserted AS (
+         INSERT INTO posts (title, url, original_url, description, content, author, image_url, episode, published_at,
+                            feed_id, guid, created_at, updated_at)
+             VALUES (@title,
+                     @url,
+                     @original_url,
+                     @description,
+                     @content,
+                     @author,
+                     @image_url,
+                     @episode,
+                     COALESCE(sqlc.narg(published_at)::TIMESTAMP, @created_at),
+                     @feed_id,
+                     @guid,
+                     @created_at,
+                     @updated_at)
+             ON CONFLICT (feed_id, guid) DO UPDATE
+                 SET title = EXCLUDED.title,
+                     url = EXCLUDED.url,
+                     original_url = EXCLUDED.original_url,
+                     description = EXCLUDED.description,
+                     content = EXCLUDED.content,
+                     author = EXCLUDED.author,
+                     image_url = EXCLUDED.image_url,
+                     episode = EXCLUDED.episode,
+                     published_at = COALESCE(sqlc.narg(published_at)::TIMESTAMP, posts.published_at),
+                     updated_at = EXCLUDED.updated_at
+                 WHERE posts.title IS DISTINCT FROM EXCLUDED.title
+                     OR posts.url IS DISTINCT FROM EXCLUDED.url
+                     OR posts.description IS DISTINCT FROM EXCLUDED.description
+                     OR posts.content IS DISTINCT FROM EXCLUDED.content
+                     OR posts.author IS DISTINCT FROM EXCLUDED.author
+                     OR posts.image_url IS DISTINCT FROM EXCLUDED.image_url
+                     OR posts.episode IS DISTINCT FROM EXCLUDED.episode
+                     OR posts.published_at IS DISTINCT FROM
+                        COALESCE(sqlc.narg(published_at)::TIMESTAMP, posts.published_at)
+             RETURNING *),
+     revision AS (
+         INSERT INTO post_revisions (post_id, title, url, description, published_at, created_at)
+             SELECT p.id, p.title, COALESCE(p.original_url, p.url), p.description, p.published_at, u.updated_at
+             FROM previous p
+                      JOIN upserted u ON u.id = p.id
+             WHERE p.title IS DISTINCT FROM u.title
+                OR p.url IS DISTINCT FROM u.url
+                OR p.description IS DISTINCT FROM u.description
+                OR p.published_at IS DISTINCT FROM u.published_at),
+     dropped_categories AS (
+         DELETE FROM post_categories
+             WHERE post_id = (SELECT id FROM previous)
+                 AND name <> ALL (@categories::TEXT[])),
+     added_categories AS (
+         INSERT INTO post_categories (post_id, name)
+             SELECT COALESCE((SELECT id FROM previous), (SELECT id FROM upserted)), UNNEST(@categories::TEXT[])
+             ON CONFLICT DO NOTHING)
+SELECT u.*, p.id IS NOT NULL AS updated
+FROM upserted u
+         LEFT JOIN previous p ON p.id = u.id;
+
+-- name: GetPost :one
+SELECT *
+FROM posts
+WHERE id = $1;
+
+-- name: GetPostRevisions :many
+SELECT *
+FROM post_revisions
+WHERE post_id = $1
+ORDER BY created_at DESC;
+
+-- name: GetRecentPostDates :many
+-- Returns the publish dates of the latest posts of a feed. Undated posts are
+-- stored with their first-seen time, which says nothing about how often the
+-- feed publishes, so they are left out.
+SELECT published_at
+FROM posts
+WHERE feed_id = $1
+  AND published_at IS NOT NULL
+  AND published_at <> created_at
+ORDER BY published_at DESC
+LIMIT $2;
 
 -- name: GetPostsForUser :many
+-- Returns the latest posts of the feeds the user follows, optionally only the
+-- ones by an author matching author or tagged with category.
 SELECT p.*
 FROM posts p
          JOIN feeds f ON f.id = p.feed_id
          JOIN feed_follows ff ON ff.feed_id = f.id
-WHERE ff.user_id = $1
+WHERE ff.user_id = @user_id
+  AND (sqlc.narg(author)::TEXT IS NULL OR p.author ILIKE '%' || sqlc.narg(author) || '%')
+  AND (sqlc.narg(category)::TEXT IS NULL OR EXISTS (SELECT 1
+                                                   FROM post_categories pc
+                                                   WHERE pc.post_id = p.id
+                                                     AND LOWER(pc.name) = LOWER(sqlc.narg(category))))
 ORDER BY p.published_at DESC NULLS LAST, p.created_at DESC
-LIMIT $2;
+LIMIT sqlc.arg('limit');
+
+-- name: GetPostCategories :many
+SELECT *
+FROM post_categories
+WHERE post_id = ANY (@post_ids::UUID[])
+ORDER BY post_id, name;
+
+-- name: SetPostEnclosures :exec
+-- Replaces the enclosures of the post with the given guid, the arrays hold
+-- one value per enclosure and zero or empty values are stored as NULL.
+WITH post AS (SELECT id
+              FROM posts
+              WHERE feed_id = @feed_id
+                AND guid = @guid),
+     dropped AS (
+         DELETE FROM post_enclosures
+             WHERE post_id = (SELECT id FROM post)
+                 AND url <> ALL (@urls::TEXT[]))
+INSERT
+INTO post_enclosures (post_id, url, mime_type, length, duration_seconds)
+SELECT post.id, e.url, NULLIF(e.mime_type, ''), NULLIF(e.length, 0), NULLIF(e.duration_seconds, 0)
+FROM post,
+     UNNEST(@urls::TEXT[], @mime_types::TEXT[], @lengths::BIGINT[], @durations::INTEGER[])
+         AS e(url, mime_type, length, duration_seconds)
+ON CONFLICT (post_id, url) DO UPDATE
+    SET mime_type        = EXCLUDED.mime_type,
+        length           = EXCLUDED.length,
+        duration_seconds = EXCLUDED.duration_seconds
+    WHERE (post_enclosures.mime_type, post_enclosures.length, post_enclosures.duration_seconds)
+              IS DISTINCT FROM (EXCLUDED.mime_type, EXCLUDED.length, EXCLUDED.duration_seconds);
+
+-- name: GetPostEnclosures :many
+SELECT *
+FROM post_enclosures
+WHERE post_id = ANY (@post_ids::UUID[])
+ORDER BY post_id, url;
+
+-- name: GetPostGuidsWithEnclosures :many
+-- Returns which of the given guids of a feed belong to posts that have
+-- enclosures stored.
+SELECT p.guid
+FROM posts p
+WHERE p.feed_id = @feed_id
+  AND p.guid = ANY (@guids::TEXT[])
+  AND EXISTS (SELECT 1 FROM post_enclosures pe WHERE pe.post_id = p.id);
+
+-- name: AdoptPostGuids :exec
+-- Posts stored before guids were kept got their URL as guid by the 008
+-- migration. An item with a guid of its own and such a post's URL takes the
+-- post over instead of being stored a second time.
+UPDATE posts p
+SET guid = i.guid
+FROM (SELECT DISTINCT ON (guid) guid, url
+      FROM UNNEST(@guids::TEXT[], @urls::TEXT[]) AS item(guid, url)
+      WHERE guid <> url
+      ORDER BY guid) AS i
+WHERE p.feed_id = @feed_id
+  AND p.guid = p.url
+  AND p.url = i.url
+  AND NOT EXISTS (SELECT 1 FROM posts taken WHERE taken.feed_id = @feed_id AND taken.guid = i.guid);
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE feeds
+    ADD COLUMN IF NOT EXISTS etag          TEXT,
+    ADD COLUMN IF NOT EXISTS last_modified TEXT;
+
+-- +goose Down
+ALTER TABLE feeds
+    DROP COLUMN IF EXISTS etag,
+    DROP COLUMN IF EXISTS last_modified;
@@ -0,0 +1,17 @@
+-- +goose Up
+ALTER TABLE feeds
+    ADD COLUMN IF NOT EXISTS title       TEXT,
+    ADD COLUMN IF NOT EXISTS description TEXT,
+    ADD COLUMN IF NOT EXISTS site_url    TEXT,
+    ADD COLUMN IF NOT EXISTS language    TEXT,
+    ADD COLUMN IF NOT EXISTS image_url   TEXT,
+    ADD COLUMN IF NOT EXISTS generator   TEXT;
+
+-- +goose Down
+ALTER TABLE feeds
+    DROP COLUMN IF EXISTS title,
+    DROP COLUMN IF EXISTS description,
+    DROP COLUMN IF EXISTS site_url,
+    DROP COLUMN IF EXISTS language,
+    DROP COLUMN IF EXISTS image_url,
+    DROP COLUMN IF EXISTS generator;
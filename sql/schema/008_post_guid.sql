@@ -0,0 +1,26 @@
+-- +goose Up
+-- guid is the identity of the item within its feed: the item guid (or Atom
+-- id), else its link, else a hash of its content. Existing posts get their
+-- URL, an item with a guid of its own takes its post over on the next fetch
+-- through AdoptPostGuids instead of being stored again.
+ALTER TABLE posts
+    ADD COLUMN IF NOT EXISTS guid TEXT;
+
+UPDATE posts
+SET guid = COALESCE(url, id::TEXT)
+WHERE guid IS NULL;
+
+ALTER TABLE posts
+    ALTER COLUMN guid SET NOT NULL,
+    DROP CONSTRAINT IF EXISTS posts_url_key;
+
+CREATE UNIQUE INDEX idx_posts_feed_guid_uq ON posts (feed_id, guid);
+CREATE INDEX idx_posts_url ON posts (url);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_posts_url;
+DROP INDEX IF EXISTS idx_posts_feed_guid_uq;
+
+ALTER TABLE posts
+    DROP COLUMN IF EXISTS guid,
+    ADD CONSTRAINT posts_url_key UNIQUE (url);
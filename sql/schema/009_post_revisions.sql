@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE post_revisions
+(
+    id           UUID      NOT NULL PRIMARY KEY DEFAULT gen_random_uuid(),
+    post_id      UUID      NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    title        TEXT      NOT NULL,
+    url          TEXT,
+    description  TEXT,
+    published_at TIMESTAMP,
+    created_at   TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_post_revisions_post_id ON post_revisions (post_id, created_at);
+
+-- +goose Down
+DROP TABLE post_revisions;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE feeds
+    ADD COLUMN IF NOT EXISTS lease_expires_at TIMESTAMP;
+
+CREATE INDEX idx_feed_lease_expires_at ON feeds (lease_expires_at);
+
+-- +goose Down
+ALTER TABLE feeds
+    DROP COLUMN IF EXISTS lease_expires_at;
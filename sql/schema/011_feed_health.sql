@@ -0,0 +1,19 @@
+-- +goose Up
+ALTER TABLE feeds
+    ADD COLUMN IF NOT EXISTS consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS last_error           TEXT,
+    ADD COLUMN IF NOT EXISTS last_status          INTEGER,
+    ADD COLUMN IF NOT EXISTS last_success_at      TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS next_fetch_at        TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS disabled_at          TIMESTAMP;
+
+CREATE INDEX idx_feed_next_fetch_at ON feeds (next_fetch_at);
+
+-- +goose Down
+ALTER TABLE feeds
+    DROP COLUMN IF EXISTS consecutive_failures,
+    DROP COLUMN IF EXISTS last_error,
+    DROP COLUMN IF EXISTS last_status,
+    DROP COLUMN IF EXISTS last_success_at,
+    DROP COLUMN IF EXISTS next_fetch_at,
+    DROP COLUMN IF EXISTS disabled_at;
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE feed_redirects
+(
+    id         UUID      NOT NULL PRIMARY KEY DEFAULT gen_random_uuid(),
+    feed_id    UUID      NOT NULL REFERENCES feeds (id) ON DELETE CASCADE,
+    from_url   TEXT      NOT NULL,
+    to_url     TEXT      NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_feed_redirects_feed_id ON feed_redirects (feed_id, created_at);
+
+-- +goose Down
+DROP TABLE feed_redirects;
@@ -0,0 +1,20 @@
+-- +goose Up
+ALTER TABLE posts
+    ADD COLUMN IF NOT EXISTS content TEXT,
+    ADD COLUMN IF NOT EXISTS author  TEXT;
+
+CREATE TABLE post_categories
+(
+    post_id UUID NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    name    TEXT NOT NULL,
+    PRIMARY KEY (post_id, name)
+);
+
+CREATE INDEX idx_post_categories_name ON post_categories (LOWER(name));
+
+-- +goose Down
+DROP TABLE post_categories;
+
+ALTER TABLE posts
+    DROP COLUMN IF EXISTS content,
+    DROP COLUMN IF EXISTS author;
@@ -0,0 +1,21 @@
+-- +goose Up
+ALTER TABLE posts
+    ADD COLUMN IF NOT EXISTS image_url TEXT,
+    ADD COLUMN IF NOT EXISTS episode   INTEGER;
+
+CREATE TABLE post_enclosures
+(
+    post_id          UUID NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    url              TEXT NOT NULL,
+    mime_type        TEXT,
+    length           BIGINT,
+    duration_seconds INTEGER,
+    PRIMARY KEY (post_id, url)
+);
+
+-- +goose Down
+DROP TABLE post_enclosures;
+
+ALTER TABLE posts
+    DROP COLUMN IF EXISTS image_url,
+    DROP COLUMN IF EXISTS episode;
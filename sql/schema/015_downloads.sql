@@ -0,0 +1,22 @@
+-- +goose Up
+-- state is downloading while a download runs, partial when it was cut short
+-- and can be resumed, complete once the file is verified and removed when
+-- retention deleted it
+CREATE TABLE downloads
+(
+    id           UUID      NOT NULL PRIMARY KEY DEFAULT gen_random_uuid(),
+    post_id      UUID      NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    url          TEXT      NOT NULL,
+    path         TEXT      NOT NULL,
+    state        TEXT      NOT NULL,
+    bytes        BIGINT    NOT NULL DEFAULT 0,
+    sha256       TEXT,
+    last_error   TEXT,
+    created_at   TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMP NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP,
+    UNIQUE (post_id, url)
+);
+
+-- +goose Down
+DROP TABLE downloads;
@@ -0,0 +1,151 @@
+-- +goose Up
+-- url of feeds and posts holds the canonical URL they are compared by and
+-- original_url the one as it was given or published, which feeds are fetched
+-- from. canonical_url mirrors canonical.URL in Go and only lives for this
+-- migration.
+-- +goose StatementBegin
+CREATE FUNCTION canonical_url(raw TEXT) RETURNS TEXT AS
+$$
+SELECT CASE
+           WHEN parts IS NULL OR LOWER(parts[1]) NOT IN ('http', 'https') THEN BTRIM(raw, E' \t\r\n')
+           ELSE 'https://' ||
+                REGEXP_REPLACE(REGEXP_REPLACE(LOWER(parts[2]), '^www\.', ''), ':(80|443)$', '') ||
+                REGEXP_REPLACE(parts[3], '/+$', '') ||
+                COALESCE((SELECT '?' || STRING_AGG(param, '&' ORDER BY param COLLATE "C")
+                          FROM REGEXP_SPLIT_TO_TABLE(SUBSTR(parts[4], 2), '&') AS param
+                          WHERE param <> ''
+                            AND LOWER(SPLIT_PART(param, '=', 1)) !~ '^(utm_.*|fbclid|gclid)$'), '') ||
+                COALESCE(parts[5], '')
+           END
+FROM (SELECT REGEXP_MATCH(BTRIM(raw, E' \t\r\n'),
+                          '^([A-Za-z][A-Za-z0-9+.-]*)://([^/?#]*)([^?#]*)(\?[^#]*)?(#.*)?$') AS parts) AS split
+$$ LANGUAGE SQL IMMUTABLE;
+-- +goose StatementEnd
+
+ALTER TABLE feeds
+    ADD COLUMN original_url TEXT;
+UPDATE feeds
+SET original_url = url;
+ALTER TABLE feeds
+    ALTER COLUMN original_url SET NOT NULL;
+
+ALTER TABLE posts
+    ADD COLUMN original_url TEXT;
+UPDATE posts
+SET original_url = url;
+
+-- guids that are URLs are canonical as well, posts that turn out to be the
+-- same within a feed are merged into the first seen one
+CREATE TEMPORARY TABLE post_merges AS
+SELECT id AS from_id,
+       FIRST_VALUE(id) OVER (PARTITION BY feed_id, canonical_url(guid) ORDER BY created_at, id) AS into_id
+FROM posts;
+
+DELETE
+FROM post_merges
+WHERE from_id = into_id;
+
+-- the kept post takes over the downloads of the merged ones so their files
+-- stay tracked and are removed by retention, unless it has a download of the
+-- same enclosure already, which then usually is the very same file
+UPDATE downloads
+SET post_id    = moved.into_id,
+    updated_at = NOW()
+FROM (SELECT DISTINCT ON (m.into_id, d.url) d.id, m.into_id
+      FROM downloads d
+               JOIN post_merges m ON m.from_id = d.post_id
+      WHERE NOT EXISTS (SELECT 1 FROM downloads kept WHERE kept.post_id = m.into_id AND kept.url = d.url)
+      ORDER BY m.into_id, d.url, d.state = 'complete' DESC, d.updated_at DESC) AS moved
+WHERE downloads.id = moved.id;
+
+DELETE
+FROM posts
+WHERE id IN (SELECT from_id FROM post_merges);
+
+DELETE
+FROM post_merges;
+
+UPDATE posts
+SET guid = canonical_url(guid),
+    url  = canonical_url(url)
+WHERE guid IS DISTINCT FROM canonical_url(guid)
+   OR url IS DISTINCT FROM canonical_url(url);
+
+-- duplicate feeds are merged into the first added one: their followers follow
+-- it, their posts missing there move over and the duplicates are deleted
+CREATE TEMPORARY TABLE feed_merges AS
+SELECT id AS from_id,
+       FIRST_VALUE(id) OVER (PARTITION BY canonical_url(url) ORDER BY created_at, id) AS into_id
+FROM feeds;
+
+DELETE
+FROM feed_merges
+WHERE from_id = into_id;
+
+INSERT INTO feed_follows (id, user_id, feed_id, created_at, updated_at)
+SELECT gen_random_uuid(), ff.user_id, m.into_id, ff.created_at, NOW()
+FROM feed_follows ff
+         JOIN feed_merges m ON m.from_id = ff.feed_id
+ON CONFLICT (user_id, feed_id) DO NOTHING;
+
+UPDATE posts
+SET feed_id    = moved.into_id,
+    updated_at = NOW()
+FROM (SELECT DISTINCT ON (m.into_id, p.guid) p.id, m.into_id
+      FROM posts p
+               JOIN feed_merges m ON m.from_id = p.feed_id
+      WHERE NOT EXISTS (SELECT 1 FROM posts kept WHERE kept.feed_id = m.into_id AND kept.guid = p.guid)
+      ORDER BY m.into_id, p.guid, p.created_at) AS moved
+WHERE posts.id = moved.id;
+
+UPDATE feed_redirects
+SET feed_id = m.into_id
+FROM feed_merges m
+WHERE feed_redirects.feed_id = m.from_id;
+
+-- the posts left behind are merged into the one with the same guid, which
+-- takes over their downloads like above
+INSERT INTO post_merges (from_id, into_id)
+SELECT p.id, kept.id
+FROM posts p
+         JOIN feed_merges m ON m.from_id = p.feed_id
+         JOIN posts kept ON kept.feed_id = m.into_id AND kept.guid = p.guid;
+
+UPDATE downloads
+SET post_id    = moved.into_id,
+    updated_at = NOW()
+FROM (SELECT DISTINCT ON (m.into_id, d.url) d.id, m.into_id
+      FROM downloads d
+               JOIN post_merges m ON m.from_id = d.post_id
+      WHERE NOT EXISTS (SELECT 1 FROM downloads kept WHERE kept.post_id = m.into_id AND kept.url = d.url)
+      ORDER BY m.into_id, d.url, d.state = 'complete' DESC, d.updated_at DESC) AS moved
+WHERE downloads.id = moved.id;
+
+DELETE
+FROM posts
+WHERE feed_id IN (SELECT from_id FROM feed_merges);
+
+DELETE
+FROM feeds
+WHERE id IN (SELECT from_id FROM feed_merges);
+
+DROP TABLE feed_merges;
+DROP TABLE post_merges;
+
+UPDATE feeds
+SET url = canonical_url(url)
+WHERE url <> canonical_url(url);
+
+DROP FUNCTION canonical_url(TEXT);
+
+-- +goose Down
+-- merged feeds and posts aren't split again
+UPDATE feeds
+SET url = original_url;
+UPDATE posts
+SET url = original_url;
+
+ALTER TABLE posts
+    DROP COLUMN IF EXISTS original_url;
+ALTER TABLE feeds
+    DROP COLUMN IF EXISTS original_url;